@@ -0,0 +1,146 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// AccessReview is the resource attributes of a SelfSubjectAccessReview, which describe the action that should be
+// checked.
+type AccessReview struct {
+	Namespace   string `json:"namespace,omitempty"`
+	Verb        string `json:"verb,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Resource    string `json:"resource,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+// AccessReviewResult is the status of a SelfSubjectAccessReview for the checked AccessReview. The Error is only set by
+// CanIBatch, when the review couldn't be checked.
+type AccessReviewResult struct {
+	Review          AccessReview `json:"review"`
+	Allowed         bool         `json:"allowed"`
+	Denied          bool         `json:"denied,omitempty"`
+	Reason          string       `json:"reason,omitempty"`
+	EvaluationError string       `json:"evaluationError,omitempty"`
+	Error           string       `json:"error,omitempty"`
+}
+
+type selfSubjectAccessReview struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		ResourceAttributes AccessReview `json:"resourceAttributes"`
+	} `json:"spec"`
+	Status struct {
+		Allowed         bool   `json:"allowed"`
+		Denied          bool   `json:"denied"`
+		Reason          string `json:"reason"`
+		EvaluationError string `json:"evaluationError"`
+	} `json:"status"`
+}
+
+// CanI checks via a SelfSubjectAccessReview if the current user is allowed to perform the given action. The url is the
+// url of the API server.
+func CanI(url string, review AccessReview, options *Options) (AccessReviewResult, error) {
+	return CanIContext(context.Background(), url, review, options)
+}
+
+// CanIContext checks the action like CanI. The request is cancelled via the context.
+func CanIContext(ctx context.Context, url string, review AccessReview, options *Options) (AccessReviewResult, error) {
+	var ssar selfSubjectAccessReview
+	ssar.APIVersion = "authorization.k8s.io/v1"
+	ssar.Kind = "SelfSubjectAccessReview"
+	ssar.Spec.ResourceAttributes = review
+
+	body, err := json.Marshal(ssar)
+	if err != nil {
+		return AccessReviewResult{}, err
+	}
+
+	data, err := DoContext(ctx, "POST", strings.TrimSuffix(url, "/")+"/apis/authorization.k8s.io/v1/selfsubjectaccessreviews", string(body), options)
+	if err != nil {
+		return AccessReviewResult{}, err
+	}
+
+	var result selfSubjectAccessReview
+	err = json.Unmarshal([]byte(data), &result)
+	if err != nil {
+		return AccessReviewResult{}, err
+	}
+
+	return AccessReviewResult{
+		Review:          review,
+		Allowed:         result.Status.Allowed,
+		Denied:          result.Status.Denied,
+		Reason:          result.Status.Reason,
+		EvaluationError: result.Status.EvaluationError,
+	}, nil
+}
+
+// CanIBatch checks multiple actions via CanI. The reviews are sent concurrently, with at most concurrency requests at
+// the same time. The results are returned in the order of the reviews. If a review fails its error is recorded in the
+// Error of its result and the results of all reviews are returned together with the first error.
+func CanIBatch(url string, reviews []AccessReview, concurrency int, options *Options) ([]AccessReviewResult, error) {
+	return CanIBatchContext(context.Background(), url, reviews, concurrency, options)
+}
+
+// CanIBatchContext checks the actions like CanIBatch. The deadline of the context bounds all reviews together, when it
+// is exceeded the remaining reviews are not sent and the error of the context is recorded for them. The results of the
+// reviews which finished before are returned.
+func CanIBatchContext(ctx context.Context, url string, reviews []AccessReview, concurrency int, options *Options) ([]AccessReviewResult, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]AccessReviewResult, len(reviews))
+	errs := make([]error, len(reviews))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index], errs[index] = CanIContext(ctx, url, reviews[index], options)
+			}
+		}()
+	}
+
+	dispatched := 0
+dispatch:
+	for dispatched < len(reviews) {
+		select {
+		case indexes <- dispatched:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	// Only the reviews which were never sent get the error of the context.
+	for index := dispatched; index < len(reviews); index++ {
+		errs[index] = ctx.Err()
+	}
+
+	var firstErr error
+	for index, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		results[index] = AccessReviewResult{Review: reviews[index], Error: err.Error()}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return results, firstErr
+}
@@ -0,0 +1,125 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCanIBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var ssar selfSubjectAccessReview
+		err := json.NewDecoder(r.Body).Decode(&ssar)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		ssar.Status.Allowed = ssar.Spec.ResourceAttributes.Verb == "get"
+		json.NewEncoder(w).Encode(ssar)
+	}))
+	defer server.Close()
+
+	reviews := []AccessReview{
+		{Namespace: "default", Verb: "get", Resource: "pods"},
+		{Namespace: "default", Verb: "delete", Resource: "pods"},
+		{Namespace: "kube-system", Verb: "get", Resource: "secrets"},
+	}
+
+	results, err := CanIBatch(server.URL, reviews, 2, nil)
+	if err != nil {
+		t.Fatalf("Could not check permissions: %s", err.Error())
+	}
+
+	for i, result := range results {
+		if result.Review != reviews[i] {
+			t.Errorf("Unexpected review at %d: %v", i, result.Review)
+		}
+
+		if result.Allowed != (reviews[i].Verb == "get") {
+			t.Errorf("Unexpected result for %v: %t", reviews[i], result.Allowed)
+		}
+	}
+}
+
+func TestCanIBatchContextDeadline(t *testing.T) {
+	done := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	reviews := []AccessReview{
+		{Namespace: "default", Verb: "get", Resource: "pods"},
+		{Namespace: "default", Verb: "list", Resource: "pods"},
+		{Namespace: "default", Verb: "delete", Resource: "pods"},
+	}
+
+	results, err := CanIBatchContext(ctx, server.URL, reviews, 1, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(results) != len(reviews) {
+		t.Fatalf("Unexpected results: %v", results)
+	}
+
+	for i, result := range results {
+		if result.Review != reviews[i] || result.Error == "" {
+			t.Errorf("Unexpected result at %d: %v", i, result)
+		}
+	}
+}
+
+func TestCanIBatchPartialResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ssar selfSubjectAccessReview
+		err := json.NewDecoder(r.Body).Decode(&ssar)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if ssar.Spec.ResourceAttributes.Resource == "secrets" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		ssar.Status.Allowed = true
+		json.NewEncoder(w).Encode(ssar)
+	}))
+	defer server.Close()
+
+	reviews := []AccessReview{
+		{Namespace: "default", Verb: "get", Resource: "pods"},
+		{Namespace: "default", Verb: "get", Resource: "secrets"},
+		{Namespace: "default", Verb: "list", Resource: "pods"},
+	}
+
+	// The deadline isn't exceeded, so that the error of the failed review and not of the context must be returned.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	results, err := CanIBatchContext(ctx, server.URL, reviews, 2, nil)
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(results) != 3 || !results[0].Allowed || results[0].Error != "" || results[1].Allowed || results[1].Error == "" || !results[2].Allowed || results[2].Error != "" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
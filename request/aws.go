@@ -0,0 +1,996 @@
+package request
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	awsrequest "github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+var (
+	// ErrExpiredCredentials is returned by the credentials preflight when the AWS credentials are expired.
+	ErrExpiredCredentials = errors.New("aws credentials are expired")
+	// ErrInvalidCredentials is returned by the credentials preflight when the AWS credentials are invalid.
+	ErrInvalidCredentials = errors.New("aws credentials are invalid")
+	// ErrClusterNotFound is returned when the EKS cluster doesn't exist.
+	ErrClusterNotFound = errors.New("eks cluster not found")
+	// ErrNodegroupNotFound is returned when the nodegroup of an EKS cluster doesn't exist.
+	ErrNodegroupNotFound = errors.New("eks nodegroup not found")
+	// ErrAccessDenied is returned when the AWS credentials don't have the permission for an EKS API call.
+	ErrAccessDenied = errors.New("aws access denied")
+	// ErrThrottled is returned when an EKS API call was throttled and all retries failed.
+	ErrThrottled = errors.New("aws request throttled")
+)
+
+// AWSOptions contains optional settings for the AWS helpers.
+type AWSOptions struct {
+	// Preflight checks the credentials via sts:GetCallerIdentity before the clusters are listed, so that expired or
+	// invalid credentials are reported as ErrExpiredCredentials or ErrInvalidCredentials.
+	Preflight bool
+	// CacheTTL is the time in seconds for which the result is stored in the cache set via SetCache. The result is
+	// cached per endpoint, region and credentials. If the value is zero the cache isn't used.
+	CacheTTL int64
+	// MaxRetries is the maximum number of retries for the AWS API calls. If the value is zero the default of the SDK is
+	// used, a negative value disables the retries.
+	MaxRetries int64
+	// Logger is called for each AWS API call which is retried, e.g. because of a ThrottlingException. The metadata
+	// added to the context via WithMetadata is appended to the messages.
+	Logger Logger
+	// IncludeAccountID adds the AWS account ID of the credentials to the result, so that the clusters can be attributed
+	// to an account. The account ID is resolved via sts:GetCallerIdentity, together with the Preflight if it is set.
+	IncludeAccountID bool
+}
+
+// AWSClusters contains the active EKS clusters and the clusters which were skipped because they are not active. The
+// AccountID is only set when the IncludeAccountID option is used.
+type AWSClusters struct {
+	AccountID string           `json:"accountID,omitempty"`
+	Clusters  []*eks.Cluster   `json:"clusters"`
+	Skipped   []SkippedCluster `json:"skipped"`
+}
+
+// SkippedCluster is an EKS cluster which was skipped because of its status, e.g. a cluster which is still creating.
+type SkippedCluster struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// AWSGetClustersContext returns all EKS clusters from AWS. The context is passed to all EKS API calls, so that the
+// deadline and cancellation of the caller are respected.
+func AWSGetClustersContext(ctx context.Context, accessKeyId, secretAccessKey, region string) (string, error) {
+	sess, err := awsSession(accessKeyId, secretAccessKey, region)
+	if err != nil {
+		return "", err
+	}
+
+	return AWSGetClustersWithSession(ctx, sess)
+}
+
+// AWSGetClustersWithSession returns all EKS clusters for the given AWS session. The session can be created once and
+// reused for multiple calls, so that the credentials and configuration are not resolved again for each call.
+func AWSGetClustersWithSession(ctx context.Context, sess *session.Session) (string, error) {
+	clusters, _, err := awsGetClusters(ctx, sess)
+	if err != nil {
+		return "", err
+	}
+
+	if clusters != nil {
+		b, err := json.Marshal(clusters)
+		if err != nil {
+			return "", err
+		}
+
+		return string(b), nil
+	}
+
+	return "", nil
+}
+
+// AWSGetClustersByTag returns all active EKS clusters from AWS, which have the given tag. EKS can't filter the
+// clusters by tag, so that the clusters are filtered by the tags from the DescribeCluster output.
+func AWSGetClustersByTag(accessKeyId, secretAccessKey, region, tagKey, tagValue string) (string, error) {
+	sess, err := awsSession(accessKeyId, secretAccessKey, region)
+	if err != nil {
+		return "", err
+	}
+
+	clusters, _, err := awsGetClusters(context.Background(), sess)
+	if err != nil {
+		return "", err
+	}
+
+	clusters = awsFilterClustersByTag(clusters, tagKey, tagValue)
+
+	if clusters != nil {
+		b, err := json.Marshal(clusters)
+		if err != nil {
+			return "", err
+		}
+
+		return string(b), nil
+	}
+
+	return "", nil
+}
+
+// awsFilterClustersByTag returns the clusters which have a tag with the given key and value.
+func awsFilterClustersByTag(clusters []*eks.Cluster, tagKey, tagValue string) []*eks.Cluster {
+	var filtered []*eks.Cluster
+
+	for _, cluster := range clusters {
+		if value, ok := cluster.Tags[tagKey]; ok && aws.StringValue(value) == tagValue {
+			filtered = append(filtered, cluster)
+		}
+	}
+
+	return filtered
+}
+
+// AWSGetClustersWithSkipped returns all active EKS clusters from AWS together with the name and status of all clusters
+// which were skipped because they are not active.
+func AWSGetClustersWithSkipped(accessKeyId, secretAccessKey, region string) (string, error) {
+	return AWSGetClustersWithOptions(accessKeyId, secretAccessKey, region, nil)
+}
+
+// AWSGetClustersWithOptions returns all active EKS clusters from AWS together with the skipped clusters, like
+// AWSGetClustersWithSkipped. The options can be nil.
+func AWSGetClustersWithOptions(accessKeyId, secretAccessKey, region string, options *AWSOptions) (string, error) {
+	return AWSGetClustersWithOptionsContext(context.Background(), accessKeyId, secretAccessKey, region, options)
+}
+
+// AWSGetClustersWithOptionsContext returns the clusters like AWSGetClustersWithOptions. The deadline of the context
+// bounds all AWS API calls together, the remaining calls are cancelled when it is exceeded.
+func AWSGetClustersWithOptionsContext(ctx context.Context, accessKeyId, secretAccessKey, region string, options *AWSOptions) (string, error) {
+	sess, err := awsSession(accessKeyId, secretAccessKey, region)
+	if err != nil {
+		return "", err
+	}
+
+	return awsGetClustersWithOptions(ctx, sess, options)
+}
+
+// awsGetClustersWithOptions returns the result of AWSGetClustersWithOptions for the given session.
+func awsGetClustersWithOptions(ctx context.Context, sess *session.Session, options *AWSOptions) (string, error) {
+	if options == nil {
+		options = &AWSOptions{}
+	}
+
+	sess = awsApplyOptions(sess, options)
+
+	cacheKind := "clusters"
+	if options.IncludeAccountID {
+		cacheKind = "clusters-with-account"
+	}
+
+	if options.CacheTTL > 0 {
+		if key, ok := awsCacheKey(sess, cacheKind); ok {
+			if value, ok := getCache().Get(key); ok {
+				return string(value), nil
+			}
+		}
+	}
+
+	var accountID string
+	if options.Preflight || options.IncludeAccountID {
+		identity, err := awsPreflight(ctx, sess)
+		if err != nil {
+			return "", err
+		}
+
+		if options.IncludeAccountID {
+			accountID = aws.StringValue(identity.Account)
+		}
+	}
+
+	clusters, skipped, err := awsGetClusters(ctx, sess)
+	if err != nil {
+		return "", err
+	}
+
+	result := AWSClusters{AccountID: accountID, Clusters: clusters, Skipped: []SkippedCluster{}}
+	if result.Clusters == nil {
+		result.Clusters = []*eks.Cluster{}
+	}
+	for _, cluster := range skipped {
+		result.Skipped = append(result.Skipped, SkippedCluster{Name: aws.StringValue(cluster.Name), Status: aws.StringValue(cluster.Status)})
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+
+	// The credentials were retrieved for the API calls, so that the key is always available here.
+	if options.CacheTTL > 0 {
+		if key, ok := awsCacheKey(sess, cacheKind); ok {
+			getCache().Set(key, b, time.Duration(options.CacheTTL)*time.Second)
+		}
+	}
+
+	return string(b), nil
+}
+
+// awsApplyOptions returns a copy of the session with the retry settings and the logger from the options.
+func awsApplyOptions(sess *session.Session, options *AWSOptions) *session.Session {
+	config := &aws.Config{}
+	if options.MaxRetries > 0 {
+		config.MaxRetries = aws.Int(int(options.MaxRetries))
+	} else if options.MaxRetries < 0 {
+		config.MaxRetries = aws.Int(0)
+	}
+
+	sess = sess.Copy(config)
+
+	if options.Logger != nil {
+		logger := options.Logger
+		sess.Handlers.AfterRetry.PushFrontNamed(awsrequest.NamedHandler{
+			Name: "kubenav.RetryLogger",
+			Fn: func(r *awsrequest.Request) {
+				// The retry state is normally set by the core AfterRetryHandler, which runs after this handler. It
+				// is set in the same way here, so that the retry decision is known before the request is retried.
+				if r.Retryable == nil {
+					r.Retryable = aws.Bool(r.ShouldRetry(r))
+				}
+
+				if r.WillRetry() {
+					message := fmt.Sprintf("retrying %s %s (retry %d of %d): %s", r.ClientInfo.ServiceName, r.Operation.Name, r.RetryCount+1, r.MaxRetries(), r.Error.Error())
+					if metadata := formatMetadata(r.Context()); metadata != "" {
+						message = message + " " + metadata
+					}
+
+					logger.Log(message)
+				}
+			},
+		})
+	}
+
+	return sess
+}
+
+// awsPreflight checks the credentials of the session via sts:GetCallerIdentity. Expired and invalid credentials are
+// returned as ErrExpiredCredentials and ErrInvalidCredentials.
+func awsPreflight(ctx context.Context, sess *session.Session) (*sts.GetCallerIdentityOutput, error) {
+	identity, err := sts.New(sess).GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok {
+			switch awsErr.Code() {
+			case "ExpiredToken", "ExpiredTokenException", "RequestExpired":
+				return nil, fmt.Errorf("%w: %s", ErrExpiredCredentials, awsErr.Message())
+			case "InvalidClientTokenId", "SignatureDoesNotMatch", "UnrecognizedClientException":
+				return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, awsErr.Message())
+			}
+		}
+
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+// awsGetClusters returns all active EKS clusters for the given session and the clusters which were skipped because
+// they are not active. The skipped clusters are returned with the full DescribeCluster output, so that callers like
+// the fleet health can use the health issues of failed clusters.
+func awsGetClusters(ctx context.Context, sess *session.Session) ([]*eks.Cluster, []*eks.Cluster, error) {
+	var clusters []*eks.Cluster
+	var skipped []*eks.Cluster
+	var names []*string
+	var nextToken *string
+
+	eksClient := eks.New(sess)
+
+	for {
+		c, err := eksClient.ListClustersWithContext(ctx, &eks.ListClustersInput{NextToken: nextToken})
+		if err != nil {
+			return nil, nil, awsError(err, ErrClusterNotFound)
+		}
+
+		names = append(names, c.Clusters...)
+
+		if c.NextToken == nil {
+			break
+		}
+
+		nextToken = c.NextToken
+	}
+
+	for _, name := range names {
+		cluster, err := awsDescribeCluster(ctx, sess, aws.StringValue(name))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if *cluster.Status == eks.ClusterStatusActive {
+			clusters = append(clusters, cluster)
+		} else {
+			skipped = append(skipped, cluster)
+		}
+	}
+
+	return clusters, skipped, nil
+}
+
+// awsDescribeGroup coalesces concurrent DescribeCluster calls for the same cluster, region and credentials.
+var awsDescribeGroup flightGroup
+
+// awsDescribeCluster returns the EKS cluster with the given name. Concurrent calls for the same cluster share one
+// DescribeCluster call, so that composite operations don't describe a cluster multiple times. The shared cluster must
+// not be modified by the caller. The shared call isn't bound to the context of a single caller, each caller only waits
+// until its own context is done.
+func awsDescribeCluster(ctx context.Context, sess *session.Session, name string) (*eks.Cluster, error) {
+	describe := func(ctx context.Context) (interface{}, error) {
+		cluster, err := eks.New(sess).DescribeClusterWithContext(ctx, &eks.DescribeClusterInput{Name: aws.String(name)})
+		if err != nil {
+			return nil, awsError(err, ErrClusterNotFound)
+		}
+
+		return cluster.Cluster, nil
+	}
+
+	// The calls are coalesced by the fingerprint of the credentials, so that calls with separate sessions for the same
+	// credentials share one call. The credentials are retrieved before, when they aren't available yet, because the
+	// DescribeCluster call needs them anyway.
+	key, ok := awsCacheKey(sess, "describe/"+name)
+	if !ok && sess.Config.Credentials != nil {
+		if _, err := sess.Config.Credentials.GetWithContext(ctx); err != nil {
+			return nil, err
+		}
+		key, ok = awsCacheKey(sess, "describe/"+name)
+	}
+
+	if !ok {
+		cluster, err := describe(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return cluster.(*eks.Cluster), nil
+	}
+
+	cluster, err := awsDescribeGroup.do(ctx, key, describe)
+	if err != nil {
+		return nil, err
+	}
+
+	return cluster.(*eks.Cluster), nil
+}
+
+// awsError returns notFound, ErrAccessDenied and ErrThrottled for the corresponding errors of the AWS SDK, so that
+// callers can distinguish them via errors.Is. The notFound error is the sentinel of the requested resource, e.g.
+// ErrClusterNotFound or ErrNodegroupNotFound. The error of the SDK is kept, so that callers can still get the
+// awserr.Error via errors.As. All other errors are returned unchanged.
+func awsError(err error, notFound error) error {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+
+	switch awsErr.Code() {
+	case eks.ErrCodeResourceNotFoundException:
+		return &awsAPIError{kind: notFound, err: awsErr}
+	case "AccessDeniedException", "AccessDenied", "UnauthorizedOperation":
+		return &awsAPIError{kind: ErrAccessDenied, err: awsErr}
+	case "ThrottlingException", "Throttling", "TooManyRequestsException", "RequestLimitExceeded":
+		return &awsAPIError{kind: ErrThrottled, err: awsErr}
+	}
+
+	return err
+}
+
+// awsAPIError is an error of the AWS SDK, which matches the sentinel error kind via errors.Is and unwraps to the
+// awserr.Error of the SDK.
+type awsAPIError struct {
+	kind error
+	err  awserr.Error
+}
+
+func (e *awsAPIError) Error() string {
+	return e.kind.Error() + ": " + e.err.Message()
+}
+
+func (e *awsAPIError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *awsAPIError) Unwrap() error {
+	return e.err
+}
+
+// CertificateAuthorityRotation reports if the certificate authority of an EKS cluster was rotated. The
+// CertificateAuthorityData is the current base64 encoded data of the cluster.
+type CertificateAuthorityRotation struct {
+	Changed                  bool   `json:"changed"`
+	CertificateAuthorityData string `json:"certificateAuthorityData"`
+}
+
+// AWSGetCertificateAuthorityRotation compares the current certificate authority data of an EKS cluster with the
+// previously stored base64 encoded certificateAuthorityData. When the data changed, cached certificate authority data
+// for the cluster is stale and must be replaced by the returned data.
+func AWSGetCertificateAuthorityRotation(accessKeyId, secretAccessKey, region, clusterName, certificateAuthorityData string) (string, error) {
+	sess, err := awsSession(accessKeyId, secretAccessKey, region)
+	if err != nil {
+		return "", err
+	}
+
+	rotation, err := awsCertificateAuthorityRotation(context.Background(), sess, clusterName, certificateAuthorityData)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(rotation)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func awsCertificateAuthorityRotation(ctx context.Context, sess *session.Session, clusterName, certificateAuthorityData string) (*CertificateAuthorityRotation, error) {
+	cluster, err := awsDescribeCluster(ctx, sess, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	if cluster.CertificateAuthority == nil || aws.StringValue(cluster.CertificateAuthority.Data) == "" {
+		return nil, fmt.Errorf("cluster %s has no certificate authority data", clusterName)
+	}
+
+	current := aws.StringValue(cluster.CertificateAuthority.Data)
+
+	return &CertificateAuthorityRotation{
+		Changed:                  strings.TrimSpace(current) != strings.TrimSpace(certificateAuthorityData),
+		CertificateAuthorityData: current,
+	}, nil
+}
+
+// ClusterRole contains the IAM role of the control plane of an EKS cluster.
+type ClusterRole struct {
+	RoleARN string `json:"roleARN"`
+}
+
+// AWSGetClusterRoleARN returns the ARN of the IAM role, which is used by the control plane of an EKS cluster to manage
+// AWS resources on behalf of the cluster.
+func AWSGetClusterRoleARN(accessKeyId, secretAccessKey, region, clusterName string) (string, error) {
+	sess, err := awsSession(accessKeyId, secretAccessKey, region)
+	if err != nil {
+		return "", err
+	}
+
+	role, err := awsGetClusterRole(context.Background(), sess, clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(role)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// awsGetClusterRole returns the IAM role of the control plane for the given cluster.
+func awsGetClusterRole(ctx context.Context, sess *session.Session, clusterName string) (*ClusterRole, error) {
+	cluster, err := awsDescribeCluster(ctx, sess, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	if cluster.RoleArn == nil {
+		return nil, fmt.Errorf("cluster %s has no role arn", clusterName)
+	}
+
+	return &ClusterRole{RoleARN: *cluster.RoleArn}, nil
+}
+
+// ClusterVPCConfig contains the network configuration of an EKS cluster.
+type ClusterVPCConfig struct {
+	VpcID                  string   `json:"vpcID"`
+	SubnetIDs              []string `json:"subnetIDs"`
+	SecurityGroupIDs       []string `json:"securityGroupIDs"`
+	ClusterSecurityGroupID string   `json:"clusterSecurityGroupID"`
+}
+
+// AWSGetClusterVPCConfig returns the VPC ID, subnet IDs and security group IDs of an EKS cluster from the
+// resourcesVpcConfig of the cluster.
+func AWSGetClusterVPCConfig(accessKeyId, secretAccessKey, region, clusterName string) (string, error) {
+	sess, err := awsSession(accessKeyId, secretAccessKey, region)
+	if err != nil {
+		return "", err
+	}
+
+	config, err := awsGetClusterVPCConfig(context.Background(), sess, clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func awsGetClusterVPCConfig(ctx context.Context, sess *session.Session, clusterName string) (*ClusterVPCConfig, error) {
+	cluster, err := awsDescribeCluster(ctx, sess, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	if cluster.ResourcesVpcConfig == nil {
+		return nil, fmt.Errorf("cluster %s has no vpc config", clusterName)
+	}
+
+	config := &ClusterVPCConfig{
+		VpcID:                  aws.StringValue(cluster.ResourcesVpcConfig.VpcId),
+		SubnetIDs:              aws.StringValueSlice(cluster.ResourcesVpcConfig.SubnetIds),
+		SecurityGroupIDs:       aws.StringValueSlice(cluster.ResourcesVpcConfig.SecurityGroupIds),
+		ClusterSecurityGroupID: aws.StringValue(cluster.ResourcesVpcConfig.ClusterSecurityGroupId),
+	}
+	if config.SubnetIDs == nil {
+		config.SubnetIDs = []string{}
+	}
+	if config.SecurityGroupIDs == nil {
+		config.SecurityGroupIDs = []string{}
+	}
+
+	return config, nil
+}
+
+// FleetHealth contains the health of all EKS clusters across multiple regions. Regions for which the clusters couldn't
+// be listed are contained in Errors with the error message, so that one failing region doesn't hide the others.
+type FleetHealth struct {
+	Clusters []ClusterHealth   `json:"clusters"`
+	Errors   map[string]string `json:"errors"`
+}
+
+// ClusterHealth contains the status and the health issues of an EKS cluster. A cluster is healthy when it is active
+// and has no health issues.
+type ClusterHealth struct {
+	Region  string               `json:"region"`
+	Name    string               `json:"name"`
+	Status  string               `json:"status"`
+	Healthy bool                 `json:"healthy"`
+	Issues  []ClusterHealthIssue `json:"issues"`
+}
+
+// ClusterHealthIssue is a health issue of an EKS cluster, e.g. a deleted subnet or security group.
+type ClusterHealthIssue struct {
+	Code        string   `json:"code"`
+	Message     string   `json:"message"`
+	ResourceIDs []string `json:"resourceIDs"`
+}
+
+// AWSGetFleetHealth returns the status and health issues of all EKS clusters in the given regions. The regions are
+// requested concurrently.
+func AWSGetFleetHealth(accessKeyId, secretAccessKey string, regions []string) (string, error) {
+	return AWSGetFleetHealthContext(context.Background(), accessKeyId, secretAccessKey, regions)
+}
+
+// AWSGetFleetHealthContext returns the health of the clusters like AWSGetFleetHealth. The deadline of the context
+// bounds the whole operation. When it is exceeded, the clusters of the finished regions are returned and the error of
+// the context is reported for the remaining regions.
+func AWSGetFleetHealthContext(ctx context.Context, accessKeyId, secretAccessKey string, regions []string) (string, error) {
+	sessions := map[string]*session.Session{}
+	for _, region := range regions {
+		sess, err := awsSession(accessKeyId, secretAccessKey, region)
+		if err != nil {
+			return "", err
+		}
+		sessions[region] = sess
+	}
+
+	health := awsGetFleetHealth(ctx, sessions)
+
+	b, err := json.Marshal(health)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// awsGetFleetHealth returns the health of the clusters for the sessions, which are keyed by their region. The clusters
+// are sorted by region and name.
+func awsGetFleetHealth(ctx context.Context, sessions map[string]*session.Session) *FleetHealth {
+	health := &FleetHealth{Clusters: []ClusterHealth{}, Errors: map[string]string{}}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for region, sess := range sessions {
+		wg.Add(1)
+		go func(region string, sess *session.Session) {
+			defer wg.Done()
+
+			clusters, skipped, err := awsGetClusters(ctx, sess)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				health.Errors[region] = err.Error()
+				return
+			}
+
+			for _, cluster := range append(clusters, skipped...) {
+				health.Clusters = append(health.Clusters, awsClusterHealth(region, cluster))
+			}
+		}(region, sess)
+	}
+
+	wg.Wait()
+
+	sort.Slice(health.Clusters, func(i, j int) bool {
+		if health.Clusters[i].Region != health.Clusters[j].Region {
+			return health.Clusters[i].Region < health.Clusters[j].Region
+		}
+		return health.Clusters[i].Name < health.Clusters[j].Name
+	})
+
+	return health
+}
+
+// awsClusterHealth returns the health of a cluster from its status and the health issues of the DescribeCluster output.
+func awsClusterHealth(region string, cluster *eks.Cluster) ClusterHealth {
+	health := ClusterHealth{
+		Region: region,
+		Name:   aws.StringValue(cluster.Name),
+		Status: aws.StringValue(cluster.Status),
+		Issues: []ClusterHealthIssue{},
+	}
+
+	if cluster.Health != nil {
+		for _, issue := range cluster.Health.Issues {
+			health.Issues = append(health.Issues, ClusterHealthIssue{
+				Code:        aws.StringValue(issue.Code),
+				Message:     aws.StringValue(issue.Message),
+				ResourceIDs: aws.StringValueSlice(issue.ResourceIds),
+			})
+		}
+	}
+
+	health.Healthy = health.Status == eks.ClusterStatusActive && len(health.Issues) == 0
+
+	return health
+}
+
+// NodegroupSummary contains the scaling and instance details of an EKS nodegroup.
+type NodegroupSummary struct {
+	Name          string   `json:"name"`
+	Status        string   `json:"status"`
+	DesiredSize   int64    `json:"desiredSize"`
+	MinSize       int64    `json:"minSize"`
+	MaxSize       int64    `json:"maxSize"`
+	InstanceTypes []string `json:"instanceTypes"`
+	CapacityType  string   `json:"capacityType"`
+	AmiType       string   `json:"amiType"`
+}
+
+// AWSGetNodegroupsSummary returns the scaling and instance details for all nodegroups of an EKS cluster.
+func AWSGetNodegroupsSummary(accessKeyId, secretAccessKey, region, clusterName string) (string, error) {
+	return AWSGetNodegroupsSummaryContext(context.Background(), accessKeyId, secretAccessKey, region, clusterName)
+}
+
+// AWSGetNodegroupsSummaryContext returns the nodegroups like AWSGetNodegroupsSummary. The deadline of the context
+// bounds all ListNodegroups and DescribeNodegroup calls together.
+func AWSGetNodegroupsSummaryContext(ctx context.Context, accessKeyId, secretAccessKey, region, clusterName string) (string, error) {
+	sess, err := awsSession(accessKeyId, secretAccessKey, region)
+	if err != nil {
+		return "", err
+	}
+
+	return awsGetNodegroupsSummary(ctx, sess, clusterName)
+}
+
+// awsGetNodegroupsSummary returns the result of AWSGetNodegroupsSummaryContext for the given session.
+func awsGetNodegroupsSummary(ctx context.Context, sess *session.Session, clusterName string) (string, error) {
+	var summaries []NodegroupSummary
+	var names []*string
+	var nextToken *string
+
+	eksClient := eks.New(sess)
+
+	for {
+		n, err := eksClient.ListNodegroupsWithContext(ctx, &eks.ListNodegroupsInput{ClusterName: aws.String(clusterName), NextToken: nextToken})
+		if err != nil {
+			return "", awsError(err, ErrClusterNotFound)
+		}
+
+		names = append(names, n.Nodegroups...)
+
+		if n.NextToken == nil {
+			break
+		}
+
+		nextToken = n.NextToken
+	}
+
+	for _, name := range names {
+		nodegroup, err := eksClient.DescribeNodegroupWithContext(ctx, &eks.DescribeNodegroupInput{ClusterName: aws.String(clusterName), NodegroupName: name})
+		if err != nil {
+			return "", awsError(err, ErrNodegroupNotFound)
+		}
+
+		summary := NodegroupSummary{
+			Name:          aws.StringValue(nodegroup.Nodegroup.NodegroupName),
+			Status:        aws.StringValue(nodegroup.Nodegroup.Status),
+			InstanceTypes: aws.StringValueSlice(nodegroup.Nodegroup.InstanceTypes),
+			CapacityType:  aws.StringValue(nodegroup.Nodegroup.CapacityType),
+			AmiType:       aws.StringValue(nodegroup.Nodegroup.AmiType),
+		}
+
+		if scalingConfig := nodegroup.Nodegroup.ScalingConfig; scalingConfig != nil {
+			summary.DesiredSize = aws.Int64Value(scalingConfig.DesiredSize)
+			summary.MinSize = aws.Int64Value(scalingConfig.MinSize)
+			summary.MaxSize = aws.Int64Value(scalingConfig.MaxSize)
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	if summaries != nil {
+		b, err := json.Marshal(summaries)
+		if err != nil {
+			return "", err
+		}
+
+		return string(b), nil
+	}
+
+	return "", nil
+}
+
+// ServiceAccountIssuer contains the service account issuer of an EKS cluster, which is used to validate projected
+// service account tokens.
+type ServiceAccountIssuer struct {
+	Issuer  string          `json:"issuer"`
+	JWKSURI string          `json:"jwksURI"`
+	JWKS    json.RawMessage `json:"jwks,omitempty"`
+}
+
+// AWSGetServiceAccountIssuer returns the service account issuer of an EKS cluster, which is the OIDC issuer of the
+// cluster, and the JWKS URI from the OpenID configuration of the issuer. If fetchJWKS is true the JSON Web Key Set is
+// also returned.
+func AWSGetServiceAccountIssuer(accessKeyId, secretAccessKey, region, clusterName string, fetchJWKS bool) (string, error) {
+	return AWSGetServiceAccountIssuerContext(context.Background(), accessKeyId, secretAccessKey, region, clusterName, fetchJWKS)
+}
+
+// AWSGetServiceAccountIssuerContext returns the issuer like AWSGetServiceAccountIssuer. The deadline of the context
+// bounds the DescribeCluster call and the requests for the OpenID configuration and the JSON Web Key Set together.
+func AWSGetServiceAccountIssuerContext(ctx context.Context, accessKeyId, secretAccessKey, region, clusterName string, fetchJWKS bool) (string, error) {
+	sess, err := awsSession(accessKeyId, secretAccessKey, region)
+	if err != nil {
+		return "", err
+	}
+
+	return awsGetServiceAccountIssuer(ctx, sess, clusterName, fetchJWKS)
+}
+
+// awsGetServiceAccountIssuer returns the result of AWSGetServiceAccountIssuerContext for the given session.
+func awsGetServiceAccountIssuer(ctx context.Context, sess *session.Session, clusterName string, fetchJWKS bool) (string, error) {
+	cluster, err := awsDescribeCluster(ctx, sess, clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	if cluster.Identity == nil || cluster.Identity.Oidc == nil || cluster.Identity.Oidc.Issuer == nil {
+		return "", fmt.Errorf("cluster %s has no oidc issuer", clusterName)
+	}
+
+	issuer := ServiceAccountIssuer{Issuer: *cluster.Identity.Oidc.Issuer}
+
+	var configuration struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+
+	data, err := httpGet(ctx, strings.TrimSuffix(issuer.Issuer, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return "", err
+	}
+
+	err = json.Unmarshal(data, &configuration)
+	if err != nil {
+		return "", err
+	}
+
+	issuer.JWKSURI = configuration.JWKSURI
+
+	if fetchJWKS {
+		issuer.JWKS, err = httpGet(ctx, issuer.JWKSURI)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	b, err := json.Marshal(issuer)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// httpGet returns the body of a GET request for a public url.
+func httpGet(ctx context.Context, url string) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// AWSGetTokenWithSession returns a bearer token for Kubernetes API requests like AWSGetToken, but uses the given AWS
+// session instead of creating a new one.
+func AWSGetTokenWithSession(sess *session.Session, clusterID string) (string, error) {
+	return awsGetToken(sess, clusterID, base64.RawURLEncoding)
+}
+
+// AWSGetTokenPadded returns a bearer token like AWSGetToken, but the presigned URL is encoded with the padded base64
+// URL encoding. Kubernetes and the aws-iam-authenticator expect the unpadded form returned by AWSGetToken, this variant
+// is only required for older tooling which can't decode unpadded tokens.
+func AWSGetTokenPadded(accessKeyId, secretAccessKey, region, clusterID string) (string, error) {
+	sess, err := awsSession(accessKeyId, secretAccessKey, region)
+	if err != nil {
+		return "", err
+	}
+
+	return awsGetToken(sess, clusterID, base64.URLEncoding)
+}
+
+// awsGetToken returns a bearer token for the given cluster. The token is a presigned sts:GetCallerIdentity URL, which
+// is only signed locally with the credentials of the session. No request is sent to AWS to generate the token. The
+// presigned URL is encoded with the given base64 encoding.
+func awsGetToken(sess *session.Session, clusterID string, encoding *base64.Encoding) (string, error) {
+	token, err := awsPresignToken(sess, clusterID, encoding)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`{"token": "%s"}`, token), nil
+}
+
+// awsPresignToken returns the bearer token for the given cluster without the JSON wrapping of awsGetToken.
+func awsPresignToken(sess *session.Session, clusterID string, encoding *base64.Encoding) (string, error) {
+	stsClient := sts.New(sess)
+
+	request, _ := stsClient.GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	request.HTTPRequest.Header.Add("x-k8s-aws-id", clusterID)
+	presignedURLString, err := request.Presign(60)
+	if err != nil {
+		return "", err
+	}
+
+	return "k8s-aws-v1." + encoding.EncodeToString([]byte(presignedURLString)), nil
+}
+
+// awsTokenLifetime is the time for which a token is accepted by EKS. The aws-iam-authenticator expires its tokens one
+// minute earlier, so that a token isn't used at the end of its lifetime.
+const awsTokenLifetime = 14 * time.Minute
+
+// ClusterConnection contains everything which is required to connect to an EKS cluster. The CertificateAuthorityData
+// is PEM encoded and the TokenExpiry is the time as Unix timestamp in seconds, after which a new token is required.
+type ClusterConnection struct {
+	Endpoint                 string `json:"endpoint"`
+	CertificateAuthorityData string `json:"certificateAuthorityData"`
+	Token                    string `json:"token"`
+	TokenExpiry              int64  `json:"tokenExpiry"`
+}
+
+// AWSGetClusterConnection returns the endpoint, certificate authority and a bearer token for an EKS cluster as
+// ClusterConnection JSON, so that a connection can be created with a single call instead of combining AWSGetClusters
+// and AWSGetToken.
+func AWSGetClusterConnection(accessKeyId, secretAccessKey, region, clusterName string) (string, error) {
+	return AWSGetClusterConnectionContext(context.Background(), accessKeyId, secretAccessKey, region, clusterName)
+}
+
+// AWSGetClusterConnectionContext returns the connection like AWSGetClusterConnection. The DescribeCluster call is
+// cancelled when the deadline of the context is exceeded.
+func AWSGetClusterConnectionContext(ctx context.Context, accessKeyId, secretAccessKey, region, clusterName string) (string, error) {
+	sess, err := awsSession(accessKeyId, secretAccessKey, region)
+	if err != nil {
+		return "", err
+	}
+
+	connection, err := awsGetClusterConnection(ctx, sess, clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(connection)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// awsGetClusterConnection returns the connection of AWSGetClusterConnectionContext for the given session.
+func awsGetClusterConnection(ctx context.Context, sess *session.Session, clusterName string) (*ClusterConnection, error) {
+	cluster, err := awsDescribeCluster(ctx, sess, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	if cluster.CertificateAuthority == nil || aws.StringValue(cluster.CertificateAuthority.Data) == "" {
+		return nil, fmt.Errorf("cluster %s has no certificate authority data", clusterName)
+	}
+
+	certificateAuthorityData, err := DecodeCertificateAuthorityData(aws.StringValue(cluster.CertificateAuthority.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	expiry := time.Now().Add(awsTokenLifetime)
+
+	token, err := awsPresignToken(sess, clusterName, base64.RawURLEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClusterConnection{
+		Endpoint:                 aws.StringValue(cluster.Endpoint),
+		CertificateAuthorityData: certificateAuthorityData,
+		Token:                    token,
+		TokenExpiry:              expiry.Unix(),
+	}, nil
+}
+
+// awsCacheKey returns a cache key for the given kind of data, which is unique for the endpoint, the region and the
+// credentials of the session. The secret access key is only included as hash. The credentials are only read when they
+// were already retrieved, so that a cache lookup never sends a request for providers like AssumeRole. Otherwise false
+// is returned and the cache must not be used.
+func awsCacheKey(sess *session.Session, kind string) (string, bool) {
+	if sess.Config.Credentials == nil || sess.Config.Credentials.IsExpired() {
+		return "", false
+	}
+
+	cred, err := sess.Config.Credentials.Get()
+	if err != nil {
+		return "", false
+	}
+
+	secret := sha256.Sum256([]byte(cred.SecretAccessKey + cred.SessionToken))
+
+	return fmt.Sprintf("aws/%s/%s/%s/%s/%x", kind, aws.StringValue(sess.Config.Endpoint), aws.StringValue(sess.Config.Region), cred.AccessKeyID, secret), true
+}
+
+// awsSession returns a new AWS session for the given static credentials and region.
+func awsSession(accessKeyId, secretAccessKey, region string) (*session.Session, error) {
+	cred := credentials.NewStaticCredentials(accessKeyId, secretAccessKey, "")
+
+	// Static credentials are retrieved without a request, so that they are available for the cache key right away.
+	cred.Get()
+
+	return session.NewSession(&aws.Config{Region: aws.String(region), Credentials: cred})
+}
@@ -0,0 +1,908 @@
+package request
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// offlineHTTPClient returns an HTTP client which fails all connection attempts, to ensure that a function doesn't
+// require network access. The returned counter contains the number of connection attempts.
+func offlineHTTPClient() (*http.Client, *int) {
+	var dials int
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dials++
+				return nil, fmt.Errorf("network is not available")
+			},
+		},
+	}, &dials
+}
+
+// fakeAWSSession returns a session for which all requests are sent to the given handler. The returned server must be
+// closed by the caller.
+func fakeAWSSession(t *testing.T, handler http.Handler) (*session.Session, *httptest.Server) {
+	server := httptest.NewServer(handler)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY", ""),
+		MaxRetries:  aws.Int(0),
+	})
+	if err != nil {
+		server.Close()
+		t.Fatalf("Could not create session: %s", err.Error())
+	}
+
+	return sess, server
+}
+
+// fakeEKSHandler returns a handler which serves the ListClusters and DescribeCluster operations for the given clusters.
+// The number of ListClusters calls is counted in listCalls.
+func fakeEKSHandler(clusters map[string]string, listCalls *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/clusters" {
+			*listCalls++
+
+			var names []string
+			for name := range clusters {
+				names = append(names, name)
+			}
+			json.NewEncoder(w).Encode(map[string][]string{"clusters": names})
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/clusters/")
+		cluster, ok := clusters[name]
+		if !ok {
+			w.Header().Set("X-Amzn-Errortype", "ResourceNotFoundException")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"message": "No cluster found for name: %s."}`, name)
+			return
+		}
+
+		fmt.Fprintf(w, `{"cluster": %s}`, cluster)
+	})
+}
+
+func TestAWSGetClustersWithSkipped(t *testing.T) {
+	accessKeyId := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	region := os.Getenv("AWS_REGION")
+
+	data, err := AWSGetClustersWithSkipped(accessKeyId, secretAccessKey, region)
+	if err != nil {
+		t.Errorf("Could not get clusters: %s", err.Error())
+	}
+
+	t.Log(data)
+}
+
+func TestAWSGetClustersWithSkippedFake(t *testing.T) {
+	var listCalls int
+
+	sess, server := fakeAWSSession(t, fakeEKSHandler(map[string]string{
+		"dev":     `{"name": "dev", "status": "ACTIVE"}`,
+		"prod":    `{"name": "prod", "status": "CREATING"}`,
+		"staging": `{"name": "staging", "status": "DELETING"}`,
+	}, &listCalls))
+	defer server.Close()
+
+	data, err := awsGetClustersWithOptions(context.Background(), sess, nil)
+	if err != nil {
+		t.Fatalf("Could not get clusters: %s", err.Error())
+	}
+
+	var clusters AWSClusters
+	err = json.Unmarshal([]byte(data), &clusters)
+	if err != nil {
+		t.Fatalf("Could not decode clusters: %s", err.Error())
+	}
+
+	if len(clusters.Clusters) != 1 || aws.StringValue(clusters.Clusters[0].Name) != "dev" {
+		t.Errorf("Unexpected clusters: %s", data)
+	}
+
+	skipped := map[string]string{}
+	for _, cluster := range clusters.Skipped {
+		skipped[cluster.Name] = cluster.Status
+	}
+
+	if len(skipped) != 2 || skipped["prod"] != "CREATING" || skipped["staging"] != "DELETING" {
+		t.Errorf("Unexpected skipped clusters: %s", data)
+	}
+}
+
+func TestAWSGetClustersWithSession(t *testing.T) {
+	var listCalls int
+
+	sess, server := fakeAWSSession(t, fakeEKSHandler(map[string]string{
+		"dev":  `{"name": "dev", "status": "ACTIVE"}`,
+		"prod": `{"name": "prod", "status": "CREATING"}`,
+	}, &listCalls))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		data, err := AWSGetClustersWithSession(context.Background(), sess)
+		if err != nil {
+			t.Fatalf("Could not get clusters: %s", err.Error())
+		}
+
+		var clusters []map[string]interface{}
+		err = json.Unmarshal([]byte(data), &clusters)
+		if err != nil {
+			t.Fatalf("Could not decode clusters: %s", err.Error())
+		}
+
+		if len(clusters) != 1 || clusters[0]["Name"] != "dev" {
+			t.Errorf("Unexpected clusters: %s", data)
+		}
+	}
+
+	if listCalls != 2 {
+		t.Errorf("Expected two ListClusters calls with the reused session, got %d", listCalls)
+	}
+}
+
+func TestAWSGetTokenWithSession(t *testing.T) {
+	var requests int32
+
+	sess, server := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+
+	data, err := AWSGetTokenWithSession(sess, "my-cluster")
+	if err != nil {
+		t.Fatalf("Could not get token: %s", err.Error())
+	}
+
+	var token struct {
+		Token string `json:"token"`
+	}
+	err = json.Unmarshal([]byte(data), &token)
+	if err != nil {
+		t.Fatalf("Could not decode token: %s", err.Error())
+	}
+
+	presignedURLString, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token.Token, "k8s-aws-v1."))
+	if err != nil {
+		t.Fatalf("Could not decode presigned url: %s", err.Error())
+	}
+
+	presignedURL, err := url.Parse(string(presignedURLString))
+	if err != nil {
+		t.Fatalf("Could not parse presigned url: %s", err.Error())
+	}
+
+	// The token is signed for the endpoint of the session, without sending a request to it.
+	if presignedURL.Scheme+"://"+presignedURL.Host != server.URL || presignedURL.Query().Get("Action") != "GetCallerIdentity" {
+		t.Errorf("Presigned url doesn't use the session: %s", presignedURL)
+	}
+
+	if !strings.Contains(presignedURL.Query().Get("X-Amz-SignedHeaders"), "x-k8s-aws-id") {
+		t.Errorf("Presigned url doesn't sign the cluster id header: %s", presignedURL)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("Token generation sent %d requests", n)
+	}
+}
+
+func TestAWSGetClustersContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := AWSGetClustersContext(ctx, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY", "us-east-1")
+	if err == nil {
+		t.Errorf("Get clusters with canceled context instead of error")
+	}
+}
+
+func TestAWSGetClustersWithOptionsCache(t *testing.T) {
+	SetCache(nil)
+	t.Cleanup(func() { SetCache(nil) })
+
+	var listCalls int
+
+	sess, server := fakeAWSSession(t, fakeEKSHandler(map[string]string{
+		"dev":  `{"name": "dev", "status": "ACTIVE"}`,
+		"prod": `{"name": "prod", "status": "CREATING"}`,
+	}, &listCalls))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		data, err := awsGetClustersWithOptions(context.Background(), sess, &AWSOptions{CacheTTL: 60})
+		if err != nil {
+			t.Fatalf("Could not get clusters: %s", err.Error())
+		}
+
+		var clusters AWSClusters
+		err = json.Unmarshal([]byte(data), &clusters)
+		if err != nil {
+			t.Fatalf("Could not decode clusters: %s", err.Error())
+		}
+
+		if len(clusters.Clusters) != 1 || *clusters.Clusters[0].Name != "dev" {
+			t.Errorf("Unexpected clusters: %s", data)
+		}
+
+		if len(clusters.Skipped) != 1 || clusters.Skipped[0] != (SkippedCluster{Name: "prod", Status: "CREATING"}) {
+			t.Errorf("Unexpected skipped clusters: %s", data)
+		}
+	}
+
+	if listCalls != 1 {
+		t.Errorf("Clusters were listed %d times instead of being cached", listCalls)
+	}
+}
+
+func TestAWSGetClustersWithOptionsCacheEndpoint(t *testing.T) {
+	SetCache(nil)
+	t.Cleanup(func() { SetCache(nil) })
+
+	var firstCalls, secondCalls int
+
+	first, firstServer := fakeAWSSession(t, fakeEKSHandler(map[string]string{"dev": `{"name": "dev", "status": "ACTIVE"}`}, &firstCalls))
+	defer firstServer.Close()
+
+	second, secondServer := fakeAWSSession(t, fakeEKSHandler(map[string]string{"prod": `{"name": "prod", "status": "ACTIVE"}`}, &secondCalls))
+	defer secondServer.Close()
+
+	for i := 0; i < 2; i++ {
+		for _, sess := range []*session.Session{first, second} {
+			_, err := awsGetClustersWithOptions(context.Background(), sess, &AWSOptions{CacheTTL: 60})
+			if err != nil {
+				t.Fatalf("Could not get clusters: %s", err.Error())
+			}
+		}
+	}
+
+	data, err := awsGetClustersWithOptions(context.Background(), second, &AWSOptions{CacheTTL: 60})
+	if err != nil {
+		t.Fatalf("Could not get clusters: %s", err.Error())
+	}
+
+	if firstCalls != 1 || secondCalls != 1 || !strings.Contains(data, `"prod"`) {
+		t.Errorf("Endpoints share the cache: %d and %d calls, %s", firstCalls, secondCalls, data)
+	}
+}
+
+func TestAWSGetClustersWithOptionsAccountID(t *testing.T) {
+	var listCalls int
+	eksHandler := fakeEKSHandler(map[string]string{"dev": `{"name": "dev", "status": "ACTIVE"}`}, &listCalls)
+
+	sess, server := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(`<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><GetCallerIdentityResult><Arn>arn:aws:iam::123456789012:user/kubenav</Arn><UserId>AIDAEXAMPLE</UserId><Account>123456789012</Account></GetCallerIdentityResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></GetCallerIdentityResponse>`))
+			return
+		}
+
+		eksHandler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	data, err := awsGetClustersWithOptions(context.Background(), sess, &AWSOptions{IncludeAccountID: true})
+	if err != nil {
+		t.Fatalf("Could not get clusters: %s", err.Error())
+	}
+
+	var clusters AWSClusters
+	err = json.Unmarshal([]byte(data), &clusters)
+	if err != nil {
+		t.Fatalf("Could not decode clusters: %s", err.Error())
+	}
+
+	if clusters.AccountID != "123456789012" || len(clusters.Clusters) != 1 {
+		t.Errorf("Unexpected clusters: %s", data)
+	}
+
+	data, err = awsGetClustersWithOptions(context.Background(), sess, nil)
+	if err != nil {
+		t.Fatalf("Could not get clusters: %s", err.Error())
+	}
+
+	if strings.Contains(data, "accountID") {
+		t.Errorf("Account ID was returned without the option: %s", data)
+	}
+}
+
+func TestAWSFilterClustersByTag(t *testing.T) {
+	var listCalls int
+
+	sess, server := fakeAWSSession(t, fakeEKSHandler(map[string]string{
+		"dev":      `{"name": "dev", "status": "ACTIVE", "tags": {"environment": "dev", "team": "payments"}}`,
+		"prod":     `{"name": "prod", "status": "ACTIVE", "tags": {"environment": "prod", "team": "payments"}}`,
+		"untagged": `{"name": "untagged", "status": "ACTIVE"}`,
+	}, &listCalls))
+	defer server.Close()
+
+	clusters, _, err := awsGetClusters(context.Background(), sess)
+	if err != nil {
+		t.Fatalf("Could not get clusters: %s", err.Error())
+	}
+
+	filtered := awsFilterClustersByTag(clusters, "environment", "prod")
+	if len(filtered) != 1 || *filtered[0].Name != "prod" {
+		t.Errorf("Unexpected clusters for environment=prod: %v", filtered)
+	}
+
+	filtered = awsFilterClustersByTag(clusters, "team", "payments")
+	if len(filtered) != 2 {
+		t.Errorf("Unexpected clusters for team=payments: %v", filtered)
+	}
+}
+
+type testLogger struct {
+	messages []string
+}
+
+func (l *testLogger) Log(message string) {
+	l.messages = append(l.messages, message)
+}
+
+func TestAWSGetClustersWithOptionsRetryLogger(t *testing.T) {
+	var listCalls int
+	handler := fakeEKSHandler(map[string]string{"dev": `{"name": "dev", "status": "ACTIVE"}`}, &listCalls)
+
+	var throttled int
+	sess, server := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if throttled < 2 {
+			throttled++
+			w.Header().Set("X-Amzn-Errortype", "ThrottlingException")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message": "Rate exceeded"}`))
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	logger := &testLogger{}
+
+	ctx := WithMetadata(context.Background(), "tenant", "payments")
+
+	_, err := awsGetClustersWithOptions(ctx, sess, &AWSOptions{MaxRetries: 3, Logger: logger})
+	if err != nil {
+		t.Fatalf("Could not get clusters: %s", err.Error())
+	}
+
+	if len(logger.messages) != 2 || !strings.Contains(logger.messages[0], "ListClusters") || !strings.Contains(logger.messages[0], "ThrottlingException") || !strings.HasSuffix(logger.messages[0], " tenant=payments") {
+		t.Errorf("Unexpected retry log messages: %v", logger.messages)
+	}
+}
+
+func TestAWSPreflight(t *testing.T) {
+	for _, tc := range []struct {
+		code string
+		err  error
+	}{
+		{code: "ExpiredToken", err: ErrExpiredCredentials},
+		{code: "InvalidClientTokenId", err: ErrInvalidCredentials},
+	} {
+		t.Run(tc.code, func(t *testing.T) {
+			sess, server := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprintf(w, `<ErrorResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><Error><Type>Sender</Type><Code>%s</Code><Message>credentials rejected</Message></Error><RequestId>1</RequestId></ErrorResponse>`, tc.code)
+			}))
+			defer server.Close()
+
+			_, err := awsPreflight(context.Background(), sess)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Unexpected preflight error: %v", err)
+			}
+		})
+	}
+}
+
+func TestAWSDescribeClusterErrors(t *testing.T) {
+	for _, tc := range []struct {
+		code   string
+		status int
+		err    error
+	}{
+		{code: "ResourceNotFoundException", status: http.StatusNotFound, err: ErrClusterNotFound},
+		{code: "AccessDeniedException", status: http.StatusForbidden, err: ErrAccessDenied},
+		{code: "ThrottlingException", status: http.StatusTooManyRequests, err: ErrThrottled},
+	} {
+		t.Run(tc.code, func(t *testing.T) {
+			sess, server := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Amzn-Errortype", tc.code)
+				w.WriteHeader(tc.status)
+				fmt.Fprintf(w, `{"message": "request failed"}`)
+			}))
+			defer server.Close()
+
+			_, err := awsDescribeCluster(context.Background(), sess, "dev")
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			var awsErr awserr.Error
+			if !errors.As(err, &awsErr) || awsErr.Code() != tc.code {
+				t.Errorf("Error of the AWS SDK was dropped: %v", err)
+			}
+		})
+	}
+}
+
+func TestAWSGetNodegroupsSummaryNotFound(t *testing.T) {
+	sess, server := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/clusters/dev/node-groups" {
+			fmt.Fprintf(w, `{"nodegroups": ["deleted"]}`)
+			return
+		}
+
+		w.Header().Set("X-Amzn-Errortype", "ResourceNotFoundException")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"message": "No node group found for name: deleted."}`)
+	}))
+	defer server.Close()
+
+	_, err := awsGetNodegroupsSummary(context.Background(), sess, "dev")
+	if !errors.Is(err, ErrNodegroupNotFound) || errors.Is(err, ErrClusterNotFound) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != "ResourceNotFoundException" {
+		t.Errorf("Error of the AWS SDK was dropped: %v", err)
+	}
+}
+
+func TestAWSDescribeClusterCoalescing(t *testing.T) {
+	var describeCalls int32
+
+	sess, server := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&describeCalls, 1)
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprintf(w, `{"cluster": {"name": "dev", "status": "ACTIVE"}}`)
+	}))
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			cluster, err := awsDescribeCluster(context.Background(), sess, "dev")
+			if err != nil || aws.StringValue(cluster.Name) != "dev" {
+				t.Errorf("Unexpected cluster: %v, %v", cluster, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&describeCalls); calls != 1 {
+		t.Errorf("Cluster was described %d times instead of once", calls)
+	}
+
+	_, err := awsDescribeCluster(context.Background(), sess, "dev")
+	if err != nil {
+		t.Fatalf("Could not describe cluster: %s", err.Error())
+	}
+
+	if calls := atomic.LoadInt32(&describeCalls); calls != 2 {
+		t.Errorf("Result of a finished describe was reused")
+	}
+}
+
+func TestAWSDescribeClusterCoalescingSessions(t *testing.T) {
+	var describeCalls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&describeCalls, 1)
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprintf(w, `{"cluster": {"name": "dev", "status": "ACTIVE"}}`)
+	}))
+	defer server.Close()
+
+	newSession := func(secretAccessKey string) *session.Session {
+		sess, err := session.NewSession(&aws.Config{
+			Region:      aws.String("us-east-1"),
+			Endpoint:    aws.String(server.URL),
+			Credentials: credentials.NewStaticCredentials("AKIDEXAMPLE", secretAccessKey, ""),
+			MaxRetries:  aws.Int(0),
+		})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err.Error())
+		}
+
+		return sess
+	}
+
+	// Each call uses its own session like the exported helpers, the last session has other credentials.
+	sessions := []*session.Session{
+		newSession("wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"),
+		newSession("wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"),
+		newSession("wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"),
+		newSession("other"),
+	}
+
+	var wg sync.WaitGroup
+	for _, sess := range sessions {
+		wg.Add(1)
+		go func(sess *session.Session) {
+			defer wg.Done()
+
+			cluster, err := awsDescribeCluster(context.Background(), sess, "dev")
+			if err != nil || aws.StringValue(cluster.Name) != "dev" {
+				t.Errorf("Unexpected cluster: %v, %v", cluster, err)
+			}
+		}(sess)
+	}
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&describeCalls); calls != 2 {
+		t.Errorf("Cluster was described %d times instead of once per credentials", calls)
+	}
+}
+
+func TestAWSCertificateAuthorityRotation(t *testing.T) {
+	var listCalls int
+
+	sess, server := fakeAWSSession(t, fakeEKSHandler(map[string]string{
+		"dev": `{"name": "dev", "status": "ACTIVE", "certificateAuthority": {"data": "Y2EtMg=="}}`,
+	}, &listCalls))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		previous string
+		changed  bool
+	}{
+		{previous: "Y2EtMg==", changed: false},
+		{previous: "Y2EtMQ==", changed: true},
+	} {
+		rotation, err := awsCertificateAuthorityRotation(context.Background(), sess, "dev", tc.previous)
+		if err != nil {
+			t.Fatalf("Could not get certificate authority rotation: %s", err.Error())
+		}
+
+		if rotation.Changed != tc.changed || rotation.CertificateAuthorityData != "Y2EtMg==" {
+			t.Errorf("Unexpected rotation for %s: %#v", tc.previous, rotation)
+		}
+	}
+}
+
+func TestAWSGetClusterConnection(t *testing.T) {
+	var listCalls int
+
+	_, certificateAuthorityData := testCertificate(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	sess, server := fakeAWSSession(t, fakeEKSHandler(map[string]string{
+		"dev": fmt.Sprintf(`{"name": "dev", "status": "ACTIVE", "endpoint": "https://dev.eks.amazonaws.com", "certificateAuthority": {"data": "%s"}}`, base64.StdEncoding.EncodeToString([]byte(certificateAuthorityData))),
+	}, &listCalls))
+	defer server.Close()
+
+	connection, err := awsGetClusterConnection(context.Background(), sess, "dev")
+	if err != nil {
+		t.Fatalf("Could not get cluster connection: %s", err.Error())
+	}
+
+	if connection.Endpoint != "https://dev.eks.amazonaws.com" || connection.CertificateAuthorityData != certificateAuthorityData {
+		t.Errorf("Unexpected cluster connection: %#v", connection)
+	}
+
+	if !strings.HasPrefix(connection.Token, "k8s-aws-v1.") {
+		t.Errorf("Unexpected token: %s", connection.Token)
+	}
+
+	if connection.TokenExpiry <= time.Now().Unix() {
+		t.Errorf("Token is already expired: %d", connection.TokenExpiry)
+	}
+
+	_, err = awsGetClusterConnection(context.Background(), sess, "nonexisting")
+	if err == nil {
+		t.Errorf("Get connection for nonexisting cluster instead of error")
+	}
+}
+
+func TestAWSGetClusterRole(t *testing.T) {
+	var listCalls int
+
+	sess, server := fakeAWSSession(t, fakeEKSHandler(map[string]string{
+		"dev":     `{"name": "dev", "status": "ACTIVE", "roleArn": "arn:aws:iam::123456789012:role/eks-cluster"}`,
+		"no-role": `{"name": "no-role", "status": "ACTIVE"}`,
+	}, &listCalls))
+	defer server.Close()
+
+	role, err := awsGetClusterRole(context.Background(), sess, "dev")
+	if err != nil {
+		t.Fatalf("Could not get role: %s", err.Error())
+	}
+
+	b, _ := json.Marshal(role)
+	if string(b) != `{"roleARN":"arn:aws:iam::123456789012:role/eks-cluster"}` {
+		t.Errorf("Unexpected role: %s", b)
+	}
+
+	_, err = awsGetClusterRole(context.Background(), sess, "no-role")
+	if err == nil {
+		t.Errorf("Get role for cluster without role arn instead of error")
+	}
+
+	_, err = awsGetClusterRole(context.Background(), sess, "missing")
+	if !errors.Is(err, ErrClusterNotFound) {
+		t.Errorf("Unexpected error for missing cluster: %v", err)
+	}
+}
+
+func TestAWSGetClusterVPCConfig(t *testing.T) {
+	var listCalls int
+
+	sess, server := fakeAWSSession(t, fakeEKSHandler(map[string]string{
+		"dev": `{"name": "dev", "status": "ACTIVE", "resourcesVpcConfig": {"vpcId": "vpc-1", "subnetIds": ["subnet-1", "subnet-2"], "securityGroupIds": ["sg-1"], "clusterSecurityGroupId": "sg-cluster"}}`,
+	}, &listCalls))
+	defer server.Close()
+
+	config, err := awsGetClusterVPCConfig(context.Background(), sess, "dev")
+	if err != nil {
+		t.Fatalf("Could not get vpc config: %s", err.Error())
+	}
+
+	b, _ := json.Marshal(config)
+	if string(b) != `{"vpcID":"vpc-1","subnetIDs":["subnet-1","subnet-2"],"securityGroupIDs":["sg-1"],"clusterSecurityGroupID":"sg-cluster"}` {
+		t.Errorf("Unexpected vpc config: %s", b)
+	}
+}
+
+func TestAWSGetFleetHealth(t *testing.T) {
+	var listCalls int
+
+	east, eastServer := fakeAWSSession(t, fakeEKSHandler(map[string]string{
+		"dev":  `{"name": "dev", "status": "ACTIVE"}`,
+		"prod": `{"name": "prod", "status": "ACTIVE", "health": {"issues": [{"code": "Ec2SubnetNotFound", "message": "Subnet was deleted", "resourceIds": ["subnet-1"]}]}}`,
+		"new":  `{"name": "new", "status": "CREATING"}`,
+		"old":  `{"name": "old", "status": "FAILED", "health": {"issues": [{"code": "IamRoleNotFound", "message": "Role was deleted", "resourceIds": ["role-1"]}]}}`,
+	}, &listCalls))
+	defer eastServer.Close()
+
+	west, westServer := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amzn-Errortype", "AccessDeniedException")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, `{"message": "not authorized"}`)
+	}))
+	defer westServer.Close()
+
+	health := awsGetFleetHealth(context.Background(), map[string]*session.Session{"us-east-1": east, "us-west-2": west})
+
+	b, _ := json.Marshal(health.Clusters)
+	if string(b) != `[{"region":"us-east-1","name":"dev","status":"ACTIVE","healthy":true,"issues":[]},{"region":"us-east-1","name":"new","status":"CREATING","healthy":false,"issues":[]},{"region":"us-east-1","name":"old","status":"FAILED","healthy":false,"issues":[{"code":"IamRoleNotFound","message":"Role was deleted","resourceIDs":["role-1"]}]},{"region":"us-east-1","name":"prod","status":"ACTIVE","healthy":false,"issues":[{"code":"Ec2SubnetNotFound","message":"Subnet was deleted","resourceIDs":["subnet-1"]}]}]` {
+		t.Errorf("Unexpected clusters: %s", b)
+	}
+
+	if len(health.Errors) != 1 || !strings.Contains(health.Errors["us-west-2"], "access denied") {
+		t.Errorf("Unexpected errors: %v", health.Errors)
+	}
+}
+
+func TestAWSGetFleetHealthDeadline(t *testing.T) {
+	var listCalls int
+	done := make(chan struct{})
+
+	east, eastServer := fakeAWSSession(t, fakeEKSHandler(map[string]string{"dev": `{"name": "dev", "status": "ACTIVE"}`}, &listCalls))
+	defer eastServer.Close()
+
+	west, westServer := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer westServer.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	health := awsGetFleetHealth(ctx, map[string]*session.Session{"us-east-1": east, "us-west-2": west})
+
+	if len(health.Clusters) != 1 || health.Clusters[0].Name != "dev" {
+		t.Errorf("Unexpected clusters: %v", health.Clusters)
+	}
+
+	if _, ok := health.Errors["us-west-2"]; !ok || len(health.Errors) != 1 {
+		t.Errorf("Unexpected errors: %v", health.Errors)
+	}
+}
+
+func TestAWSGetNodegroupsSummary(t *testing.T) {
+	accessKeyId := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	region := os.Getenv("AWS_REGION")
+	clusterName := os.Getenv("AWS_CLUSTER_ID")
+
+	data, err := AWSGetNodegroupsSummary(accessKeyId, secretAccessKey, region, clusterName)
+	if err != nil {
+		t.Errorf("Could not get nodegroups: %s", err.Error())
+	}
+
+	t.Log(data)
+}
+
+func TestAWSGetNodegroupsSummaryFake(t *testing.T) {
+	sess, server := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/clusters/dev/node-groups":
+			if r.URL.Query().Get("nextToken") == "" {
+				fmt.Fprintf(w, `{"nodegroups": ["default"], "nextToken": "page-2"}`)
+			} else {
+				fmt.Fprintf(w, `{"nodegroups": ["spot"]}`)
+			}
+		case "/clusters/dev/node-groups/default":
+			fmt.Fprintf(w, `{"nodegroup": {"nodegroupName": "default", "status": "ACTIVE", "scalingConfig": {"desiredSize": 3, "minSize": 1, "maxSize": 5}, "instanceTypes": ["m5.large"], "capacityType": "ON_DEMAND", "amiType": "AL2_x86_64"}}`)
+		case "/clusters/dev/node-groups/spot":
+			fmt.Fprintf(w, `{"nodegroup": {"nodegroupName": "spot", "status": "UPDATING", "instanceTypes": ["c5.large", "c5a.large"], "capacityType": "SPOT", "amiType": "AL2_x86_64"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	data, err := awsGetNodegroupsSummary(context.Background(), sess, "dev")
+	if err != nil {
+		t.Fatalf("Could not get nodegroups: %s", err.Error())
+	}
+
+	var summaries []NodegroupSummary
+	err = json.Unmarshal([]byte(data), &summaries)
+	if err != nil {
+		t.Fatalf("Could not decode nodegroups: %s", err.Error())
+	}
+
+	expected := []NodegroupSummary{
+		{Name: "default", Status: "ACTIVE", DesiredSize: 3, MinSize: 1, MaxSize: 5, InstanceTypes: []string{"m5.large"}, CapacityType: "ON_DEMAND", AmiType: "AL2_x86_64"},
+		{Name: "spot", Status: "UPDATING", InstanceTypes: []string{"c5.large", "c5a.large"}, CapacityType: "SPOT", AmiType: "AL2_x86_64"},
+	}
+
+	if !reflect.DeepEqual(summaries, expected) {
+		t.Errorf("Unexpected nodegroups: %s", data)
+	}
+}
+
+func TestAWSGetTokenOffline(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		encoding *base64.Encoding
+	}{
+		{name: "raw", encoding: base64.RawURLEncoding},
+		{name: "padded", encoding: base64.URLEncoding},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			httpClient, dials := offlineHTTPClient()
+
+			sess, err := session.NewSession(&aws.Config{
+				Region:      aws.String("us-east-1"),
+				Credentials: credentials.NewStaticCredentials("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY", ""),
+				HTTPClient:  httpClient,
+			})
+			if err != nil {
+				t.Fatalf("Could not create session: %s", err.Error())
+			}
+
+			data, err := awsGetToken(sess, "my-cluster", tc.encoding)
+			if err != nil {
+				t.Fatalf("Could not get token: %s", err.Error())
+			}
+
+			if *dials != 0 {
+				t.Errorf("Token generation opened %d connections", *dials)
+			}
+
+			var token struct {
+				Token string `json:"token"`
+			}
+			err = json.Unmarshal([]byte(data), &token)
+			if err != nil {
+				t.Fatalf("Could not decode token: %s", err.Error())
+			}
+
+			if !strings.HasPrefix(token.Token, "k8s-aws-v1.") {
+				t.Fatalf("Unexpected token prefix: %s", token.Token)
+			}
+
+			presignedURLString, err := tc.encoding.DecodeString(strings.TrimPrefix(token.Token, "k8s-aws-v1."))
+			if err != nil {
+				t.Fatalf("Could not decode presigned url: %s", err.Error())
+			}
+
+			presignedURL, err := url.Parse(string(presignedURLString))
+			if err != nil {
+				t.Fatalf("Could not parse presigned url: %s", err.Error())
+			}
+
+			if presignedURL.Host != "sts.amazonaws.com" || presignedURL.Query().Get("Action") != "GetCallerIdentity" {
+				t.Errorf("Presigned url is not a GetCallerIdentity url: %s", presignedURL)
+			}
+
+			if presignedURL.Query().Get("X-Amz-Signature") == "" {
+				t.Errorf("Presigned url is not signed: %s", presignedURL)
+			}
+
+			if !strings.Contains(presignedURL.Query().Get("X-Amz-SignedHeaders"), "x-k8s-aws-id") {
+				t.Errorf("Presigned url doesn't sign the cluster id header: %s", presignedURL)
+			}
+		})
+	}
+}
+
+func TestAWSGetServiceAccountIssuer(t *testing.T) {
+	accessKeyId := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	region := os.Getenv("AWS_REGION")
+	clusterName := os.Getenv("AWS_CLUSTER_ID")
+
+	data, err := AWSGetServiceAccountIssuer(accessKeyId, secretAccessKey, region, clusterName, true)
+	if err != nil {
+		t.Errorf("Could not get service account issuer: %s", err.Error())
+	}
+
+	t.Log(data)
+}
+
+func TestAWSGetServiceAccountIssuerFake(t *testing.T) {
+	var issuerURL string
+
+	sess, server := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/clusters/dev":
+			fmt.Fprintf(w, `{"cluster": {"name": "dev", "status": "ACTIVE", "identity": {"oidc": {"issuer": "%s/id/EXAMPLE/"}}}}`, issuerURL)
+		case "/clusters/no-oidc":
+			fmt.Fprintf(w, `{"cluster": {"name": "no-oidc", "status": "ACTIVE"}}`)
+		case "/id/EXAMPLE/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer": "%s/id/EXAMPLE", "jwks_uri": "%s/id/EXAMPLE/keys"}`, issuerURL, issuerURL)
+		case "/id/EXAMPLE/keys":
+			fmt.Fprintf(w, `{"keys":[{"kty":"RSA","kid":"1"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	issuerURL = server.URL
+
+	for _, fetchJWKS := range []bool{false, true} {
+		data, err := awsGetServiceAccountIssuer(context.Background(), sess, "dev", fetchJWKS)
+		if err != nil {
+			t.Fatalf("Could not get service account issuer: %s", err.Error())
+		}
+
+		var issuer ServiceAccountIssuer
+		err = json.Unmarshal([]byte(data), &issuer)
+		if err != nil {
+			t.Fatalf("Could not decode service account issuer: %s", err.Error())
+		}
+
+		if issuer.Issuer != server.URL+"/id/EXAMPLE/" || issuer.JWKSURI != server.URL+"/id/EXAMPLE/keys" {
+			t.Errorf("Unexpected issuer: %s", data)
+		}
+
+		if fetchJWKS && string(issuer.JWKS) != `{"keys":[{"kty":"RSA","kid":"1"}]}` || !fetchJWKS && issuer.JWKS != nil {
+			t.Errorf("Unexpected JWKS for fetchJWKS %t: %s", fetchJWKS, data)
+		}
+	}
+
+	_, err := awsGetServiceAccountIssuer(context.Background(), sess, "no-oidc", false)
+	if err == nil {
+		t.Errorf("Get issuer for cluster without oidc issuer instead of error")
+	}
+}
@@ -0,0 +1,80 @@
+package request
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache stores the results of helpers which support caching, e.g. the cluster list of AWSGetClustersWithOptions. The
+// default cache stores the values in memory. A custom implementation (e.g. backed by Redis or a file) can be set via
+// SetCache, to keep the values across restarts or share them between multiple instances. Tokens like the one of
+// AWSGetToken are not cached, because they are signed locally without a request to AWS and a shared cache would store
+// bearer tokens outside of the process.
+type Cache interface {
+	// Get returns the value for the key and false when the key doesn't exist or is expired.
+	Get(key string) ([]byte, bool)
+	// Set stores the value for the key for the given time to live.
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+var (
+	cache   Cache = newMemoryCache()
+	cacheMu sync.RWMutex
+)
+
+// SetCache replaces the cache which is used by all helpers. If c is nil the default in memory cache is used.
+func SetCache(c Cache) {
+	if c == nil {
+		c = newMemoryCache()
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache = c
+}
+
+// getCache returns the current cache.
+func getCache() Cache {
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	return cache
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// memoryCache is the default Cache, which stores the values in memory.
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: make(map[string]memoryCacheEntry)}
+}
+
+func (c *memoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return entry.value, true
+}
+
+func (c *memoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = memoryCacheEntry{value: value, expires: time.Now().Add(ttl)}
+}
@@ -0,0 +1,25 @@
+package request
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	c := newMemoryCache()
+
+	c.Set("a", []byte("value"), time.Minute)
+	c.Set("b", []byte("expired"), -time.Second)
+
+	if value, ok := c.Get("a"); !ok || string(value) != "value" {
+		t.Errorf("Unexpected value for a: %s, %t", value, ok)
+	}
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Get expired value for b")
+	}
+
+	if _, ok := c.Get("c"); ok {
+		t.Errorf("Get value for nonexisting key c")
+	}
+}
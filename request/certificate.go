@@ -0,0 +1,197 @@
+package request
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certificateSANs returns the DNS and IP subject alternative names of the certificate.
+func certificateSANs(cert *x509.Certificate) []string {
+	var sans []string
+
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	return sans
+}
+
+// hostnameError adds the expected host name and the subject alternative names of the certificate presented by the
+// server to the error, when the TLS verification failed because of a name mismatch. All other errors are returned
+// unchanged.
+func hostnameError(err error) error {
+	var hostnameErr x509.HostnameError
+	if !errors.As(err, &hostnameErr) || hostnameErr.Certificate == nil {
+		return err
+	}
+
+	return fmt.Errorf("certificate is not valid for %s, the certificate is valid for %s: %w", hostnameErr.Host, strings.Join(certificateSANs(hostnameErr.Certificate), ", "), err)
+}
+
+// applyClockSkewTolerance replaces the certificate verification of the TLS config of the transport, so that the
+// validity period of the server certificates is checked with the given tolerance. The chain is verified for the current
+// time and, if that fails because of the validity period, for the current time minus and plus the tolerance, so that a
+// certificate which just expired or isn't valid yet because of a clock skew is accepted. All other checks, like the
+// host name, are the same as for the default verification.
+//
+// The server name of the connection state is empty for IP addresses, so that the certificate is verified against the
+// ServerName of the TLS config or, if it isn't set, the host of the request which opened the connection. The host is
+// recorded by the returned round tripper, which must be used instead of the transport, so that the certificate of a
+// redirect target is verified for the host of the redirect. The requests of a client are sent one after another, so
+// that the recorded host is the host of the connection which is verified.
+func applyClockSkewTolerance(transport *http.Transport, tolerance time.Duration) http.RoundTripper {
+	tlsConfig := transport.TLSClientConfig
+	roots := tlsConfig.RootCAs
+
+	var mu sync.Mutex
+	var host string
+
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("server did not present a certificate")
+		}
+
+		serverName := tlsConfig.ServerName
+		if serverName == "" {
+			mu.Lock()
+			serverName = host
+			mu.Unlock()
+		}
+
+		if serverName == "" {
+			return errors.New("no host name to verify the server certificate")
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range cs.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		now := time.Now()
+
+		var verifyErr error
+		for _, currentTime := range []time.Time{now, now.Add(-tolerance), now.Add(tolerance)} {
+			_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+				DNSName:       serverName,
+				CurrentTime:   currentTime,
+			})
+			if err == nil {
+				return nil
+			}
+
+			// Only an invalid validity period can be fixed by the tolerance, all other errors are returned directly.
+			var invalidErr x509.CertificateInvalidError
+			if !errors.As(err, &invalidErr) || invalidErr.Reason != x509.Expired {
+				return err
+			}
+
+			if verifyErr == nil {
+				verifyErr = err
+			}
+		}
+
+		return verifyErr
+	}
+
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		host = req.URL.Hostname()
+		mu.Unlock()
+
+		return transport.RoundTrip(req)
+	})
+}
+
+// decryptClientKey decrypts a PEM encoded private key, which is encrypted with the legacy PEM encryption, and returns
+// the unencrypted PEM encoded key. Unencrypted keys are returned unchanged. Encrypted PKCS #8 keys are not supported by
+// the standard library.
+func decryptClientKey(clientKeyData, passphrase string) (string, error) {
+	block, _ := pem.Decode([]byte(clientKeyData))
+	if block == nil {
+		return "", errors.New("no pem block found in client key")
+	}
+
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return "", errors.New("encrypted pkcs #8 client keys are not supported, the key must be converted to the legacy pem encryption or decrypted")
+	}
+
+	if !x509.IsEncryptedPEMBlock(block) {
+		return clientKeyData, nil
+	}
+
+	der, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt client key: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})), nil
+}
+
+// FetchServerCertificates performs a TLS handshake with the server of the url and returns the certificate chain
+// presented by the server, without sending an HTTP request. The chain isn't verified, so that the certificates of an
+// untrusted server can be inspected before they are trusted. The timeout is the maximum time in seconds for the
+// connection and the handshake, if it is zero a timeout of 30 seconds is used.
+func FetchServerCertificates(rawURL string, timeout int64) ([]*x509.Certificate, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %q: https scheme and host are required", rawURL)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	if timeout == 0 {
+		timeout = 30
+	}
+
+	dialer := &net.Dialer{Timeout: time.Duration(timeout) * time.Second}
+
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates, nil
+}
+
+// DecodeCertificateAuthorityData decodes base64 encoded certificate authority data, like the certificateAuthority.data
+// of an EKS cluster from AWSGetClusters, to the PEM encoded data which is expected by the CertificateAuthorityData
+// option. An error is returned when the decoded data doesn't contain a PEM encoded certificate.
+func DecodeCertificateAuthorityData(data string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return "", err
+	}
+
+	block, _ := pem.Decode(decoded)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", errors.New("certificate authority data doesn't contain a pem encoded certificate")
+	}
+
+	return string(decoded), nil
+}
@@ -0,0 +1,305 @@
+package request
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serverCertificateAuthorityData returns the PEM encoded certificate of a TLS test server.
+func serverCertificateAuthorityData(server *httptest.Server) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+}
+
+func TestDoWithOptionsHostnameError(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The certificate of the test server is only valid for example.com and the loopback addresses.
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	_, err := DoWithOptions("GET", url, "", &Options{CertificateAuthorityData: serverCertificateAuthorityData(server)})
+	if err == nil {
+		t.Fatalf("Get response for invalid host name instead of error")
+	}
+
+	if !strings.Contains(err.Error(), "not valid for localhost") || !strings.Contains(err.Error(), "example.com") || !strings.Contains(err.Error(), "127.0.0.1") {
+		t.Errorf("Error doesn't contain the host name and the subject alternative names: %s", err.Error())
+	}
+}
+
+func TestDoRawPeerCertificateSANs(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	resp, err := DoRaw(context.Background(), "GET", server.URL, "", &Options{CertificateAuthorityData: serverCertificateAuthorityData(server)})
+	if err != nil {
+		t.Fatalf("Could not start request: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if sans := strings.Join(resp.PeerCertificateSANs(), ", "); !strings.Contains(sans, "example.com") || !strings.Contains(sans, "127.0.0.1") {
+		t.Errorf("Unexpected subject alternative names: %s", sans)
+	}
+}
+
+// testCertificate returns a self-signed certificate for 127.0.0.1 with the given validity period and the PEM encoded
+// certificate.
+func testCertificate(t *testing.T, notBefore, notAfter time.Time) (tls.Certificate, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kubenav"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Could not create certificate: %s", err.Error())
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestDoWithOptionsClockSkewTolerance(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+	}{
+		{name: "expired", notBefore: time.Now().Add(-time.Hour), notAfter: time.Now().Add(-30 * time.Second)},
+		{name: "not yet valid", notBefore: time.Now().Add(30 * time.Second), notAfter: time.Now().Add(time.Hour)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cert, certificateAuthorityData := testCertificate(t, tc.notBefore, tc.notAfter)
+
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+			server.StartTLS()
+			defer server.Close()
+
+			_, err := DoWithOptions("GET", server.URL, "", &Options{CertificateAuthorityData: certificateAuthorityData})
+			if err == nil {
+				t.Errorf("Get response without tolerance instead of error")
+			}
+
+			_, err = DoWithOptions("GET", server.URL, "", &Options{CertificateAuthorityData: certificateAuthorityData, ClockSkewTolerance: 10})
+			if err == nil {
+				t.Errorf("Get response for skew larger than the tolerance instead of error")
+			}
+
+			_, err = DoWithOptions("GET", server.URL, "", &Options{CertificateAuthorityData: certificateAuthorityData, ClockSkewTolerance: 120})
+			if err != nil {
+				t.Errorf("Could not get response with tolerance: %s", err.Error())
+			}
+
+			url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+			_, err = DoWithOptions("GET", url, "", &Options{CertificateAuthorityData: certificateAuthorityData, ClockSkewTolerance: 120})
+			if err == nil || !strings.Contains(err.Error(), "not valid for localhost") {
+				t.Errorf("Unexpected error for invalid host name: %v", err)
+			}
+		})
+	}
+}
+
+func TestDoWithOptionsClockSkewToleranceRedirect(t *testing.T) {
+	cert, certificateAuthorityData := testCertificate(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/redirected" {
+			http.Redirect(w, r, "https://"+strings.Replace(r.Host, "127.0.0.1", "localhost", 1)+"/redirected", http.StatusFound)
+			return
+		}
+		w.Write([]byte("redirected"))
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	server.StartTLS()
+	defer server.Close()
+
+	// The certificate is only valid for 127.0.0.1, so that the redirect to localhost must fail with and without the
+	// tolerance.
+	for _, tolerance := range []int64{0, 120} {
+		_, err := DoWithOptions("GET", server.URL, "", &Options{CertificateAuthorityData: certificateAuthorityData, ClockSkewTolerance: tolerance})
+		if err == nil || !strings.Contains(err.Error(), "localhost") {
+			t.Errorf("Unexpected error for redirect to invalid host name with tolerance %d: %v", tolerance, err)
+		}
+	}
+}
+
+func TestDoWithOptionsClockSkewToleranceIPHost(t *testing.T) {
+	cert, certificateAuthorityData := testCertificate(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("Could not listen on 127.0.0.2: %s", err.Error())
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	server.StartTLS()
+	defer server.Close()
+
+	// The certificate is only valid for 127.0.0.1, so that a request to 127.0.0.2 must fail with and without the
+	// tolerance.
+	for _, tolerance := range []int64{0, 60} {
+		_, err := DoWithOptions("GET", server.URL, "", &Options{CertificateAuthorityData: certificateAuthorityData, ClockSkewTolerance: tolerance})
+		if err == nil || !strings.Contains(err.Error(), "127.0.0.2") {
+			t.Errorf("Unexpected error for invalid ip address with tolerance %d: %v", tolerance, err)
+		}
+	}
+
+	redirect := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL, http.StatusFound)
+	}))
+	redirect.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	redirect.StartTLS()
+	defer redirect.Close()
+
+	// The redirect from 127.0.0.1 to 127.0.0.2 must be verified for the ip address of the redirect target.
+	_, err = DoWithOptions("GET", redirect.URL, "", &Options{CertificateAuthorityData: certificateAuthorityData, ClockSkewTolerance: 60})
+	if err == nil || !strings.Contains(err.Error(), "127.0.0.2") {
+		t.Errorf("Unexpected error for redirect to invalid ip address: %v", err)
+	}
+}
+
+func TestDoWithOptionsClientKeyPassphrase(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kubenav-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Could not create certificate: %s", err.Error())
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Could not marshal key: %s", err.Error())
+	}
+
+	encryptedBlock, err := x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", keyDER, []byte("secret"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("Could not encrypt key: %s", err.Error())
+	}
+
+	clientCertificateData := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	clientKeyData := string(pem.EncodeToMemory(encryptedBlock))
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	options := &Options{
+		CertificateAuthorityData: serverCertificateAuthorityData(server),
+		ClientCertificateData:    clientCertificateData,
+		ClientKeyData:            clientKeyData,
+		ClientKeyPassphrase:      "secret",
+	}
+
+	data, err := DoWithOptions("GET", server.URL, "", options)
+	if err != nil {
+		t.Fatalf("Could not send request with encrypted client key: %s", err.Error())
+	}
+
+	if data != "kubenav-client" {
+		t.Errorf("Unexpected client certificate: %s", data)
+	}
+
+	options.ClientKeyPassphrase = "wrong"
+	_, err = DoWithOptions("GET", server.URL, "", options)
+	if err == nil {
+		t.Errorf("Send request with wrong passphrase instead of error")
+	}
+}
+
+func TestFetchServerCertificates(t *testing.T) {
+	var requests int
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	certs, err := FetchServerCertificates(server.URL, 5)
+	if err != nil {
+		t.Fatalf("Could not fetch server certificates: %s", err.Error())
+	}
+
+	if len(certs) != 1 || !certs[0].Equal(server.Certificate()) {
+		t.Errorf("Unexpected certificates: %v", certs)
+	}
+
+	if requests != 0 {
+		t.Errorf("HTTP request was sent to fetch the certificates")
+	}
+
+	_, err = FetchServerCertificates(strings.Replace(server.URL, "https", "http", 1), 5)
+	if err == nil {
+		t.Errorf("Fetch certificates for http url instead of error")
+	}
+}
+
+func TestDecodeCertificateAuthorityData(t *testing.T) {
+	_, certificateAuthorityData := testCertificate(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	data, err := DecodeCertificateAuthorityData(base64.StdEncoding.EncodeToString([]byte(certificateAuthorityData)))
+	if err != nil {
+		t.Fatalf("Could not decode certificate authority data: %s", err.Error())
+	}
+
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE" {
+		t.Fatalf("Decoded data is not a pem encoded certificate: %s", data)
+	}
+
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		t.Errorf("Could not parse certificate: %s", err.Error())
+	}
+
+	if _, err := httpClientForRootCAs(data, "", "", false); err != nil {
+		t.Errorf("Decoded data can't be used as certificate authority: %s", err.Error())
+	}
+
+	_, err = DecodeCertificateAuthorityData(base64.StdEncoding.EncodeToString([]byte("no certificate")))
+	if err == nil {
+		t.Errorf("Decode data without certificate instead of error")
+	}
+}
@@ -0,0 +1,45 @@
+package request
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// deadlineDialContext returns a DialContext function for an http.Transport, which wraps the connections of the dialer
+// in a deadlineConn with the given timeout.
+func deadlineDialContext(dialer *net.Dialer, timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return &deadlineConn{Conn: conn, timeout: timeout}, nil
+	}
+}
+
+// deadlineConn is a connection which fails a read or write, when it doesn't complete within the timeout. The deadline
+// is renewed before each operation, so that a slow transfer succeeds as long as data arrives within the timeout.
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Read(p []byte) (int, error) {
+	err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	if err != nil {
+		return 0, err
+	}
+
+	return c.Conn.Read(p)
+}
+
+func (c *deadlineConn) Write(p []byte) (int, error) {
+	err := c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if err != nil {
+		return 0, err
+	}
+
+	return c.Conn.Write(p)
+}
@@ -0,0 +1,50 @@
+package request
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDoWithOptionsIdleTimeout(t *testing.T) {
+	done := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(w, "line %d\n", i)
+			w.(http.Flusher).Flush()
+
+			if r.URL.Path == "/stalled" && i == 1 {
+				<-done
+				return
+			}
+
+			time.Sleep(400 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	// The transfer takes longer than the idle timeout, but it must succeed, because data arrives within the timeout.
+	data, err := DoWithOptions("GET", server.URL+"/progressing", "", &Options{IdleTimeout: 1, Timeout: 10})
+	if err != nil {
+		t.Errorf("Progressing transfer failed: %s", err.Error())
+	}
+
+	if data != "line 0\nline 1\nline 2\nline 3\n" {
+		t.Errorf("Unexpected response: %s", data)
+	}
+
+	start := time.Now()
+	_, err = DoWithOptions("GET", server.URL+"/stalled", "", &Options{IdleTimeout: 1, Timeout: 10})
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Errorf("Unexpected error for stalled transfer: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Stalled transfer was detected after %s instead of the idle timeout", elapsed)
+	}
+}
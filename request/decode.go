@@ -0,0 +1,96 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrUnexpectedContentType is returned by DoJSON when the Content-Type of the response isn't JSON, e.g. for the
+	// HTML login or error page of a misconfigured proxy.
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+)
+
+// contentTypeSnippetBytes is the maximum length of the body, which is included in an ErrUnexpectedContentType error.
+const contentTypeSnippetBytes = 200
+
+// DoJSON runs the given HTTP request and decodes the JSON response into out. Numbers are decoded as json.Number when
+// out contains an interface{} value, so that large integers like resource versions don't lose precision. An empty
+// response body (e.g. for a 204) isn't an error, out is left unchanged. The OperationTimeouts of the options are
+// selected by the method. Before the body is decoded the Content-Type of the response must be application/json or
+// another JSON media type like application/merge-patch+json, otherwise ErrUnexpectedContentType is returned with the
+// requested and received content types and the beginning of the body. A response without a Content-Type is decoded.
+func DoJSON(method, url, body string, options *Options, out interface{}) error {
+	resp, data, err := doRead(context.Background(), method, url, body, operationOptions(options, methodOperation(method)))
+	if err != nil {
+		return err
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	err = checkJSONContentType(resp.Header, data, options)
+	if err != nil {
+		return err
+	}
+
+	return decodeJSON(data, out)
+}
+
+// checkJSONContentType returns an ErrUnexpectedContentType error, when the Content-Type header isn't a JSON media
+// type.
+func checkJSONContentType(header http.Header, data []byte, options *Options) error {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+
+	snippet := data
+	if len(snippet) > contentTypeSnippetBytes {
+		snippet = snippet[:contentTypeSnippetBytes]
+		for len(snippet) > 0 && !utf8.Valid(snippet) {
+			snippet = snippet[:len(snippet)-1]
+		}
+	}
+
+	return fmt.Errorf("%w: requested %s, received %s: %s", ErrUnexpectedContentType, requestedAccept(options), contentType, strings.TrimSpace(string(snippet)))
+}
+
+// requestedAccept returns the Accept header, which is sent for the options.
+func requestedAccept(options *Options) string {
+	if options != nil {
+		for key, values := range options.Headers {
+			if http.CanonicalHeaderKey(key) == "Accept" && len(values) > 0 {
+				return strings.Join(values, ", ")
+			}
+		}
+	}
+
+	return "application/json"
+}
+
+// decodeJSON decodes the data into out and keeps numbers as json.Number. Empty data or data which only contains
+// whitespace is ignored.
+func decodeJSON(data []byte, out interface{}) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(out)
+}
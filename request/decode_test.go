@@ -0,0 +1,109 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDoJSONUseNumber(t *testing.T) {
+	// 2^53 + 1 can't be represented exactly as float64.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"metadata": {"name": "default", "resourceVersion": 9007199254740993}}`))
+	}))
+	defer server.Close()
+
+	var namespace map[string]interface{}
+	err := DoJSON("GET", server.URL+"/api/v1/namespaces/default", "", nil, &namespace)
+	if err != nil {
+		t.Fatalf("Could not get namespace: %s", err.Error())
+	}
+
+	resourceVersion, ok := namespace["metadata"].(map[string]interface{})["resourceVersion"].(json.Number)
+	if !ok {
+		t.Fatalf("Resource version is not decoded as json.Number: %#v", namespace["metadata"])
+	}
+
+	if resourceVersion.String() != "9007199254740993" {
+		t.Errorf("Unexpected resource version: %s", resourceVersion)
+	}
+}
+
+func TestDoJSONEmptyBody(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		statusCode int
+		body       string
+	}{
+		{name: "200", statusCode: http.StatusOK, body: ""},
+		{name: "200 whitespace", statusCode: http.StatusOK, body: " \n"},
+		{name: "204", statusCode: http.StatusNoContent},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statusCode)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			var out map[string]interface{}
+			err := DoJSON("DELETE", server.URL+"/api/v1/namespaces/default", "", nil, &out)
+			if err != nil {
+				t.Errorf("Could not handle empty body: %s", err.Error())
+			}
+
+			if out != nil {
+				t.Errorf("Output is not zero valued: %v", out)
+			}
+		})
+	}
+}
+
+func TestDoJSONContentType(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		headers     http.Header
+		expected    string
+	}{
+		{name: "json", contentType: "application/json; charset=utf-8"},
+		{name: "json suffix", contentType: "application/merge-patch+json"},
+		{name: "no content type"},
+		{name: "html", contentType: "text/html", expected: "unexpected content type: requested application/json, received text/html: <html><body>Sign in</body></html>"},
+		{name: "accept header", contentType: "text/html", headers: http.Header{"accept": []string{"application/json;as=Table;v=v1;g=meta.k8s.io"}}, expected: "requested application/json;as=Table;v=v1;g=meta.k8s.io, received text/html"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header()["Content-Type"] = []string{tc.contentType}
+				if tc.expected != "" {
+					w.Write([]byte("<html><body>Sign in</body></html>\n"))
+					return
+				}
+				w.Write([]byte(`{"kind": "Namespace"}`))
+			}))
+			defer server.Close()
+
+			var out map[string]interface{}
+			err := DoJSON("GET", server.URL+"/api/v1/namespaces/default", "", &Options{Headers: tc.headers}, &out)
+
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("Could not decode response: %s", err.Error())
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrUnexpectedContentType) {
+				t.Fatalf("Expected ErrUnexpectedContentType, got %v", err)
+			}
+
+			if !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Unexpected error message: %s", err.Error())
+			}
+		})
+	}
+}
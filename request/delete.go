@@ -0,0 +1,60 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// DeleteOptions are the options for a delete request. When ResourceVersion or UID is set, they are sent as
+// preconditions, so that the server rejects the delete with a conflict if the object was modified or replaced since it
+// was read.
+type DeleteOptions struct {
+	ResourceVersion string
+	UID             string
+}
+
+// body returns the DeleteOptions body for the delete request or an empty string when no precondition is set.
+func (o DeleteOptions) body() (string, error) {
+	if o.ResourceVersion == "" && o.UID == "" {
+		return "", nil
+	}
+
+	type preconditions struct {
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+		UID             string `json:"uid,omitempty"`
+	}
+
+	b, err := json.Marshal(struct {
+		Kind          string        `json:"kind"`
+		APIVersion    string        `json:"apiVersion"`
+		Preconditions preconditions `json:"preconditions"`
+	}{
+		Kind:          "DeleteOptions",
+		APIVersion:    "v1",
+		Preconditions: preconditions{ResourceVersion: o.ResourceVersion, UID: o.UID},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// Delete deletes the object with the given name. The base is the url of the API server.
+func Delete(base string, gvr GroupVersionResource, namespace, name string, opts DeleteOptions, options *Options) (string, error) {
+	if name == "" {
+		return "", errors.New("name is required for a delete request")
+	}
+
+	deleteURL, err := ResourceURL(base, gvr, namespace, name)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := opts.body()
+	if err != nil {
+		return "", err
+	}
+
+	return DoWithOptions("DELETE", deleteURL, body, operationOptions(options, operationDelete))
+}
@@ -0,0 +1,60 @@
+package request
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDelete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var deleteOptions struct {
+			Preconditions struct {
+				ResourceVersion string `json:"resourceVersion"`
+				UID             string `json:"uid"`
+			} `json:"preconditions"`
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if len(body) > 0 {
+			json.Unmarshal(body, &deleteOptions)
+		}
+
+		if r.Method != "DELETE" || r.URL.Path != "/api/v1/namespaces/default/configmaps/config" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if deleteOptions.Preconditions.UID != "" && deleteOptions.Preconditions.UID != "uid-1" {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"kind": "Status", "message": "Precondition failed: UID in precondition: uid-0, UID in object meta: uid-1", "code": 409}`))
+			return
+		}
+
+		w.Write([]byte(deleteOptions.Preconditions.ResourceVersion))
+	}))
+	defer server.Close()
+
+	gvr := GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+	data, err := Delete(server.URL, gvr, "default", "config", DeleteOptions{ResourceVersion: "42", UID: "uid-1"}, nil)
+	if err != nil {
+		t.Errorf("Could not delete object: %s", err.Error())
+	}
+
+	if data != "42" {
+		t.Errorf("Resource version precondition was not sent: %s", data)
+	}
+
+	_, err = Delete(server.URL, gvr, "default", "config", DeleteOptions{UID: "uid-0"}, nil)
+	if err == nil || err.Error() != "Precondition failed: UID in precondition: uid-0, UID in object meta: uid-1" {
+		t.Errorf("Unexpected error for modified object: %v", err)
+	}
+
+	_, err = Delete(server.URL, gvr, "default", "", DeleteOptions{}, nil)
+	if err == nil {
+		t.Errorf("Deleted object without name instead of error")
+	}
+}
@@ -0,0 +1,156 @@
+package request
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffEntry is a single field of a diff between two objects. The Path is a JSON pointer to the field and Operation is
+// "added", "changed" or "removed". Old and New are the JSON values of the field, they are empty for added and removed
+// fields.
+type DiffEntry struct {
+	Path      string          `json:"path"`
+	Operation string          `json:"operation"`
+	Old       json.RawMessage `json:"old,omitempty"`
+	New       json.RawMessage `json:"new,omitempty"`
+}
+
+// diffIgnoredPaths are fields which are changed by the server for every dry-run request, so that they would show up in
+// each diff.
+var diffIgnoredPaths = map[string]bool{
+	"/metadata/managedFields":   true,
+	"/metadata/resourceVersion": true,
+	"/metadata/generation":      true,
+}
+
+// Diff returns a field level diff between the current object and the result of a dry-run request for the object, e.g.
+// a server-side apply with dryRun=All. The returned entries are sorted by their path. Fields which are always updated
+// by the server, like the resourceVersion and the managedFields, are ignored.
+func Diff(current, dryRun string) (string, error) {
+	var currentObject, dryRunObject interface{}
+
+	err := decodeJSON([]byte(current), &currentObject)
+	if err != nil {
+		return "", err
+	}
+
+	err = decodeJSON([]byte(dryRun), &dryRunObject)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := diff("", currentObject, dryRunObject, []DiffEntry{})
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// diff appends the differences between the old and new value at the given path to the entries.
+func diff(path string, old, new interface{}, entries []DiffEntry) ([]DiffEntry, error) {
+	if diffIgnoredPaths[path] {
+		return entries, nil
+	}
+
+	switch oldValue := old.(type) {
+	case map[string]interface{}:
+		if newValue, ok := new.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(oldValue)+len(newValue))
+			for key := range oldValue {
+				keys = append(keys, key)
+			}
+			for key := range newValue {
+				if _, ok := oldValue[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+
+			var err error
+			for _, key := range keys {
+				fieldPath := path + "/" + escapeJSONPointer(key)
+				o, oldOk := oldValue[key]
+				n, newOk := newValue[key]
+
+				switch {
+				case !oldOk:
+					entries, err = appendDiffEntry(entries, fieldPath, "added", nil, n)
+				case !newOk:
+					entries, err = appendDiffEntry(entries, fieldPath, "removed", o, nil)
+				default:
+					entries, err = diff(fieldPath, o, n, entries)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return entries, nil
+		}
+
+	case []interface{}:
+		if newValue, ok := new.([]interface{}); ok {
+			var err error
+			for i := 0; i < len(oldValue) || i < len(newValue); i++ {
+				itemPath := path + "/" + strconv.Itoa(i)
+
+				switch {
+				case i >= len(oldValue):
+					entries, err = appendDiffEntry(entries, itemPath, "added", nil, newValue[i])
+				case i >= len(newValue):
+					entries, err = appendDiffEntry(entries, itemPath, "removed", oldValue[i], nil)
+				default:
+					entries, err = diff(itemPath, oldValue[i], newValue[i], entries)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return entries, nil
+		}
+	}
+
+	if reflect.DeepEqual(old, new) {
+		return entries, nil
+	}
+
+	return appendDiffEntry(entries, path, "changed", old, new)
+}
+
+// appendDiffEntry appends an entry with the JSON encoded old and new values to the entries.
+func appendDiffEntry(entries []DiffEntry, path, operation string, old, new interface{}) ([]DiffEntry, error) {
+	entry := DiffEntry{Path: path, Operation: operation}
+
+	if operation != "added" {
+		b, err := json.Marshal(old)
+		if err != nil {
+			return nil, err
+		}
+		entry.Old = b
+	}
+
+	if operation != "removed" {
+		b, err := json.Marshal(new)
+		if err != nil {
+			return nil, err
+		}
+		entry.New = b
+	}
+
+	return append(entries, entry), nil
+}
+
+// escapeJSONPointer escapes a key for the usage in a JSON pointer, see RFC 6901.
+func escapeJSONPointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
@@ -0,0 +1,35 @@
+package request
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	current := `{
+		"metadata": {"name": "nginx", "resourceVersion": "1", "labels": {"app": "nginx", "app.kubernetes.io/version": "1.17"}},
+		"spec": {"replicas": 1, "ports": [80, 443], "paused": false}
+	}`
+	dryRun := `{
+		"metadata": {"name": "nginx", "resourceVersion": "2", "labels": {"app": "nginx", "tier": "web"}},
+		"spec": {"replicas": 3, "ports": [80], "paused": false}
+	}`
+
+	data, err := Diff(current, dryRun)
+	if err != nil {
+		t.Fatalf("Could not create diff: %s", err.Error())
+	}
+
+	expected := `[{"path":"/metadata/labels/app.kubernetes.io~1version","operation":"removed","old":"1.17"},{"path":"/metadata/labels/tier","operation":"added","new":"web"},{"path":"/spec/ports/1","operation":"removed","old":443},{"path":"/spec/replicas","operation":"changed","old":1,"new":3}]`
+	if data != expected {
+		t.Errorf("Unexpected diff: %s", data)
+	}
+
+	data, err = Diff(current, current)
+	if err != nil {
+		t.Fatalf("Could not create diff: %s", err.Error())
+	}
+
+	if data != "[]" {
+		t.Errorf("Unexpected diff for equal objects: %s", data)
+	}
+}
@@ -0,0 +1,93 @@
+package request
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// ErrDigestMismatch is returned when the response body doesn't match the digest sent by the server.
+var ErrDigestMismatch = errors.New("response body doesn't match digest")
+
+// digestAlgorithms are the supported algorithms of the Content-Digest and Digest headers.
+var digestAlgorithms = map[string]func() hash.Hash{
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+// verifyDigest verifies the body against the Content-Digest header or, if it isn't present, the Digest header. If none
+// of the headers is present the body isn't verified. Digests with an unsupported algorithm (e.g. MD5) are skipped, all
+// digests with a supported algorithm must match the body.
+func verifyDigest(header http.Header, body []byte) error {
+	var value string
+	var contentDigest bool
+
+	if values := header["Content-Digest"]; len(values) > 0 {
+		value = strings.Join(values, ",")
+		contentDigest = true
+	} else if values := header["Digest"]; len(values) > 0 {
+		value = strings.Join(values, ",")
+	} else {
+		return nil
+	}
+
+	digests, err := parseDigestHeader(value, contentDigest)
+	if err != nil {
+		return err
+	}
+
+	for algorithm, digest := range digests {
+		newHash := digestAlgorithms[algorithm]
+
+		expected, err := base64.StdEncoding.DecodeString(digest)
+		if err != nil {
+			return fmt.Errorf("invalid %s digest: %s", algorithm, err.Error())
+		}
+
+		h := newHash()
+		h.Write(body)
+
+		if !bytes.Equal(h.Sum(nil), expected) {
+			return fmt.Errorf("%w: %s", ErrDigestMismatch, algorithm)
+		}
+	}
+
+	return nil
+}
+
+// parseDigestHeader returns the digests with a supported algorithm by lower case algorithm. The Content-Digest header
+// wraps the base64 encoded digest in colons (e.g. "sha-256=:...:"), the Digest header contains the plain base64 value
+// (e.g. "SHA-256=...").
+func parseDigestHeader(value string, contentDigest bool) (map[string]string, error) {
+	digests := make(map[string]string)
+
+	for _, member := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(member), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid digest header %q", value)
+		}
+
+		algorithm := strings.ToLower(parts[0])
+		if _, ok := digestAlgorithms[algorithm]; !ok {
+			continue
+		}
+
+		digest := parts[1]
+		if contentDigest {
+			if len(digest) < 2 || !strings.HasPrefix(digest, ":") || !strings.HasSuffix(digest, ":") {
+				return nil, fmt.Errorf("invalid digest header %q", value)
+			}
+			digest = digest[1 : len(digest)-1]
+		}
+
+		digests[algorithm] = digest
+	}
+
+	return digests, nil
+}
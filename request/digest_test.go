@@ -0,0 +1,48 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDoWithOptionsVerifyDigest(t *testing.T) {
+	// sha-256 of `{"kind": "Namespace"}`
+	const digest = "oLMTnAw63flarSEdrDs2neNBMhPfnmqVwlbUU/o6n5c="
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		value  string
+		body   string
+		err    error
+	}{
+		{name: "content-digest", header: "Content-Digest", value: "sha-256=:" + digest + ":", body: `{"kind": "Namespace"}`},
+		{name: "digest", header: "Digest", value: "SHA-256=" + digest, body: `{"kind": "Namespace"}`},
+		{name: "mismatch", header: "Content-Digest", value: "sha-256=:" + digest + ":", body: `{"kind": "Pod"}`, err: ErrDigestMismatch},
+		{name: "no header", body: `{"kind": "Pod"}`},
+		{name: "unsupported algorithm", header: "Digest", value: "MD5=HUXZLQLMuI/KZ5KDcJPcOA==", body: `{"kind": "Pod"}`},
+		{name: "unsupported and mismatch", header: "Digest", value: "MD5=HUXZLQLMuI/KZ5KDcJPcOA==,SHA-256=" + digest, body: `{"kind": "Pod"}`, err: ErrDigestMismatch},
+		{name: "unsupported and match", header: "Content-Digest", value: "md5=:HUXZLQLMuI/KZ5KDcJPcOA==:, sha-256=:" + digest + ":", body: `{"kind": "Namespace"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.header != "" {
+					w.Header().Set(tc.header, tc.value)
+				}
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			data, err := DoWithOptions("GET", server.URL, "", &Options{VerifyDigest: true})
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tc.err == nil && data != tc.body {
+				t.Errorf("Unexpected response: %s", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package request
+
+import (
+	"fmt"
+	"io"
+)
+
+// The stream indexes of the channel.k8s.io protocols, which are used by the exec and attach subresources. Each frame of
+// the upgraded connection starts with the index of the stream it belongs to.
+const (
+	execStdin  = 0
+	execStdout = 1
+	execStderr = 2
+	execError  = 3
+	execResize = 4
+)
+
+// FrameReader reads the frames of an upgraded exec or attach connection, e.g. the binary messages of a WebSocket
+// connection. ReadFrame returns io.EOF when the connection was closed.
+type FrameReader interface {
+	ReadFrame() ([]byte, error)
+}
+
+// ExecStreams contains the demultiplexed output of an exec or attach connection. The Error stream contains the Status
+// object of the API server, when the command failed or exited with a non-zero exit code. The streams are pipes, so
+// that all of them must be read concurrently, otherwise a stream which isn't read blocks the other streams.
+type ExecStreams struct {
+	Stdout io.Reader
+	Stderr io.Reader
+	Error  io.Reader
+}
+
+// DemuxExec splits the frames of the reader by their leading stream index into the stdout, stderr and error streams.
+// All streams return io.EOF when the reader is closed, or the error of the reader when reading a frame failed. Frames
+// for the stdin and resize streams are ignored, because they are only sent by the client.
+func DemuxExec(frames FrameReader) *ExecStreams {
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	errorReader, errorWriter := io.Pipe()
+
+	writers := map[byte]*io.PipeWriter{
+		execStdout: stdoutWriter,
+		execStderr: stderrWriter,
+		execError:  errorWriter,
+	}
+
+	go func() {
+		err := demuxExecFrames(frames, writers)
+
+		for _, writer := range writers {
+			writer.CloseWithError(err)
+		}
+	}()
+
+	return &ExecStreams{Stdout: stdoutReader, Stderr: stderrReader, Error: errorReader}
+}
+
+// demuxExecFrames writes the data of each frame to the writer of its stream until reading a frame fails. For a closed
+// reader nil is returned, so that the pipes are closed with io.EOF.
+func demuxExecFrames(frames FrameReader, writers map[byte]*io.PipeWriter) error {
+	for {
+		frame, err := frames.ReadFrame()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// A frame without data is sent when a stream is opened, it doesn't have to be forwarded.
+		if len(frame) < 2 {
+			continue
+		}
+
+		switch frame[0] {
+		case execStdin, execResize:
+			continue
+		}
+
+		writer, ok := writers[frame[0]]
+		if !ok {
+			return fmt.Errorf("unknown exec stream %d", frame[0])
+		}
+
+		_, err = writer.Write(frame[1:])
+		if err != nil {
+			return err
+		}
+	}
+}
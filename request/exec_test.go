@@ -0,0 +1,90 @@
+package request
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+type testFrameReader struct {
+	frames [][]byte
+	err    error
+}
+
+func (r *testFrameReader) ReadFrame() ([]byte, error) {
+	if len(r.frames) == 0 {
+		return nil, r.err
+	}
+
+	frame := r.frames[0]
+	r.frames = r.frames[1:]
+
+	return frame, nil
+}
+
+// readExecStreams reads all streams concurrently and returns their data and errors.
+func readExecStreams(streams *ExecStreams) ([3]string, [3]error) {
+	var data [3]string
+	var errs [3]error
+
+	done := make(chan struct{})
+	for i, reader := range []io.Reader{streams.Stdout, streams.Stderr, streams.Error} {
+		go func(i int, reader io.Reader) {
+			b, err := ioutil.ReadAll(reader)
+			data[i], errs[i] = string(b), err
+			done <- struct{}{}
+		}(i, reader)
+	}
+
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+
+	return data, errs
+}
+
+func TestDemuxExec(t *testing.T) {
+	streams := DemuxExec(&testFrameReader{
+		frames: [][]byte{
+			{1}, {2}, {3},
+			append([]byte{1}, "hello "...),
+			append([]byte{2}, "warning\n"...),
+			append([]byte{1}, "world\n"...),
+			append([]byte{0}, "ignored"...),
+			append([]byte{3}, `{"status": "Success"}`...),
+		},
+		err: io.EOF,
+	})
+
+	data, errs := readExecStreams(streams)
+
+	if data != [3]string{"hello world\n", "warning\n", `{"status": "Success"}`} {
+		t.Errorf("Unexpected streams: %q", data)
+	}
+
+	if errs != [3]error{} {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+}
+
+func TestDemuxExecError(t *testing.T) {
+	connectionErr := errors.New("connection reset")
+
+	streams := DemuxExec(&testFrameReader{
+		frames: [][]byte{append([]byte{1}, "partial"...)},
+		err:    connectionErr,
+	})
+
+	data, errs := readExecStreams(streams)
+
+	if data[0] != "partial" {
+		t.Errorf("Unexpected stdout: %s", data[0])
+	}
+
+	for _, err := range errs {
+		if err != connectionErr {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+}
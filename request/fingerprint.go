@@ -0,0 +1,103 @@
+package request
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"hash"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// TLSConfigFingerprint returns a stable SHA-256 fingerprint of the TLS settings of the options: the certificate
+// authorities, the client certificate and key, InsecureSkipTLSVerify, ClockSkewTolerance and the host name of the
+// Server, which is verified against the server certificate. Two options with the same fingerprint result in the same
+// TLS configuration, so that the fingerprint can be used as key for caching clients or to check whether two
+// configurations are equivalent. The certificate authorities are compared as a set of certificates, so that their
+// order and the formatting of the PEM data don't change the fingerprint. Credentials like the Token aren't part of
+// the fingerprint.
+func TLSConfigFingerprint(options *Options) string {
+	if options == nil {
+		options = &Options{}
+	}
+
+	h := sha256.New()
+
+	authorities := pemBlocks(options.CertificateAuthorityData)
+	sort.Strings(authorities)
+	writeFingerprintField(h, "certificate-authorities", strconv.Itoa(len(authorities)))
+	for _, authority := range authorities {
+		writeFingerprintField(h, "certificate-authority", authority)
+	}
+
+	clientKeyData := options.ClientKeyData
+	if options.ClientKeyPassphrase != "" {
+		// The encrypted key contains a random salt, so that the decrypted key is used when possible. Otherwise only the
+		// encrypted key is used, the passphrase is never part of the fingerprint, because the fingerprint could be used
+		// to guess the passphrase offline.
+		decrypted, err := decryptClientKey(clientKeyData, options.ClientKeyPassphrase)
+		if err == nil {
+			clientKeyData = decrypted
+		}
+	}
+
+	clientCertificates := pemBlocks(options.ClientCertificateData)
+	writeFingerprintField(h, "client-certificates", strconv.Itoa(len(clientCertificates)))
+	for _, certificate := range clientCertificates {
+		writeFingerprintField(h, "client-certificate", certificate)
+	}
+
+	clientKeys := pemBlocks(clientKeyData)
+	writeFingerprintField(h, "client-keys", strconv.Itoa(len(clientKeys)))
+	for _, key := range clientKeys {
+		writeFingerprintField(h, "client-key", key)
+	}
+
+	var serverName string
+	if u, err := url.Parse(options.Server); err == nil {
+		serverName = u.Hostname()
+	}
+
+	writeFingerprintField(h, "server-name", serverName)
+	writeFingerprintField(h, "insecure-skip-tls-verify", strconv.FormatBool(options.InsecureSkipTLSVerify))
+	writeFingerprintField(h, "clock-skew-tolerance", strconv.FormatInt(options.ClockSkewTolerance, 10))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// pemBlocks returns the type and DER data of all PEM blocks in the data, so that the headers, line breaks and comments
+// between the blocks are ignored. Data without PEM blocks is returned as is, so that invalid data still changes the
+// fingerprint.
+func pemBlocks(data string) []string {
+	var blocks []string
+
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		blocks = append(blocks, block.Type+"\n"+string(block.Bytes))
+	}
+
+	if len(blocks) == 0 && data != "" {
+		blocks = append(blocks, data)
+	}
+
+	return blocks
+}
+
+// writeFingerprintField writes the name and value with their lengths to the hash, so that the boundaries between the
+// fields are unambiguous.
+func writeFingerprintField(h hash.Hash, name, value string) {
+	for _, s := range []string{name, value} {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(s)))
+		h.Write(length[:])
+		h.Write([]byte(s))
+	}
+}
@@ -0,0 +1,98 @@
+package request
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTLSConfigFingerprint(t *testing.T) {
+	first, firstCA := testCertificate(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	_, secondCA := testCertificate(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	keyDER, err := x509.MarshalECPrivateKey(first.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatalf("Could not marshal key: %s", err.Error())
+	}
+	clientKey := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+
+	base := Options{
+		CertificateAuthorityData: firstCA + secondCA,
+		ClientCertificateData:    firstCA,
+		ClientKeyData:            clientKey,
+		Token:                    "token",
+		Server:                   "https://kubernetes.default.svc:6443",
+	}
+	fingerprint := TLSConfigFingerprint(&base)
+
+	same := base
+	same.CertificateAuthorityData = "# ca bundle\n" + secondCA + "\n" + firstCA
+	same.Token = "other-token"
+	same.Server = "https://kubernetes.default.svc"
+	if TLSConfigFingerprint(&same) != fingerprint {
+		t.Errorf("Expected the same fingerprint for equivalent options")
+	}
+
+	for name, modify := range map[string]func(o *Options){
+		"certificate authority":    func(o *Options) { o.CertificateAuthorityData = firstCA },
+		"client certificate":       func(o *Options) { o.ClientCertificateData = secondCA },
+		"client key":               func(o *Options) { o.ClientKeyData = "" },
+		"server name":              func(o *Options) { o.Server = "https://127.0.0.1:6443" },
+		"insecure skip tls verify": func(o *Options) { o.InsecureSkipTLSVerify = true },
+		"clock skew tolerance":     func(o *Options) { o.ClockSkewTolerance = 60 },
+		"invalid data":             func(o *Options) { o.CertificateAuthorityData = strings.TrimPrefix(firstCA, "-----BEGIN") },
+	} {
+		modified := base
+		modify(&modified)
+
+		if TLSConfigFingerprint(&modified) == fingerprint {
+			t.Errorf("Expected another fingerprint when the %s changes", name)
+		}
+	}
+
+	if TLSConfigFingerprint(nil) != TLSConfigFingerprint(&Options{}) {
+		t.Errorf("Expected the same fingerprint for nil and empty options")
+	}
+}
+
+func TestTLSConfigFingerprintClientKeyPassphrase(t *testing.T) {
+	first, _ := testCertificate(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	keyDER, err := x509.MarshalECPrivateKey(first.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatalf("Could not marshal key: %s", err.Error())
+	}
+	clientKey := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+
+	encryptedBlock, err := x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", keyDER, []byte("secret"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("Could not encrypt key: %s", err.Error())
+	}
+	encryptedKey := string(pem.EncodeToMemory(encryptedBlock))
+
+	decrypted := TLSConfigFingerprint(&Options{ClientKeyData: encryptedKey, ClientKeyPassphrase: "secret"})
+	if decrypted != TLSConfigFingerprint(&Options{ClientKeyData: clientKey}) {
+		t.Errorf("Expected the fingerprint of the decrypted key")
+	}
+
+	// The legacy PEM encryption can't detect every wrong passphrase, so that a passphrase is used which fails.
+	passphrase := "wrong"
+	for i := 0; ; i++ {
+		if _, err := decryptClientKey(encryptedKey, passphrase); err != nil {
+			break
+		}
+		passphrase = "wrong-" + strconv.Itoa(i)
+	}
+
+	// Without the right passphrase only the encrypted key is used, so that the fingerprint doesn't depend on the
+	// passphrase.
+	wrong := TLSConfigFingerprint(&Options{ClientKeyData: encryptedKey, ClientKeyPassphrase: passphrase})
+	if wrong == decrypted || wrong != TLSConfigFingerprint(&Options{ClientKeyData: encryptedKey}) {
+		t.Errorf("Expected the fingerprint of the encrypted key for a wrong passphrase")
+	}
+}
@@ -0,0 +1,114 @@
+package request
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// flightGroup coalesces concurrent calls with the same key, so that only the first call is executed and all other
+// callers wait for and share its result.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done     chan struct{}
+	cancel   context.CancelFunc
+	waiters  int
+	value    interface{}
+	err      error
+	panicked bool
+	panicVal interface{}
+}
+
+// do executes fn for the key, unless a call for the same key is already in flight. In that case do waits for the
+// running call and returns its result. The call runs with a context which is detached from the deadline and
+// cancellation of the callers, so that a caller which gives up early doesn't fail the call for all other callers. Each
+// caller waits until the call is done or its own context is done. When the last waiting caller gives up, the context of
+// the call is cancelled and the key is released, so that a hanging call doesn't block all later callers. A panic of fn
+// is passed on to all waiting callers.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*flightCall{}
+	}
+
+	call, ok := g.calls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(detachedContext{parent: ctx})
+
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+
+		go g.run(callCtx, key, call, fn)
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		g.leave(key, call)
+		return nil, ctx.Err()
+	}
+
+	if call.panicked {
+		panic(call.panicVal)
+	}
+
+	return call.value, call.err
+}
+
+// leave removes a waiting caller from the call. When it was the last caller, the call is cancelled and the key is
+// released.
+func (g *flightGroup) leave(key string, call *flightCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	call.cancel()
+}
+
+// run executes fn and releases the waiting callers, also when fn panics.
+func (g *flightGroup) run(ctx context.Context, key string, call *flightCall, fn func(ctx context.Context) (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.panicked = true
+			call.panicVal = r
+		}
+
+		g.mu.Lock()
+		if g.calls[key] == call {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+
+		close(call.done)
+		call.cancel()
+	}()
+
+	call.value, call.err = fn(ctx)
+}
+
+// detachedContext keeps the values of its parent, like the metadata for log messages, but not its deadline and
+// cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,166 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFlightGroup(t *testing.T) {
+	var g flightGroup
+
+	value, err := g.do(context.Background(), "a", func(ctx context.Context) (interface{}, error) { return 1, nil })
+	if err != nil || value != 1 {
+		t.Errorf("Unexpected result: %v, %v", value, err)
+	}
+
+	_, err = g.do(context.Background(), "a", func(ctx context.Context) (interface{}, error) { return nil, errors.New("failed") })
+	if err == nil || err.Error() != "failed" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestFlightGroupCallerDeadline(t *testing.T) {
+	var g flightGroup
+
+	started := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(started)
+
+		select {
+		case <-time.After(200 * time.Millisecond):
+			return 1, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		_, err := g.do(ctx, "a", fn)
+		if err != context.DeadlineExceeded {
+			t.Errorf("Expected the deadline of the first caller, got %v", err)
+		}
+	}()
+
+	<-started
+
+	value, err := g.do(context.Background(), "a", fn)
+	if err != nil || value != 1 {
+		t.Errorf("Waiting caller got the error of the first caller: %v, %v", value, err)
+	}
+
+	wg.Wait()
+}
+
+func TestFlightGroupPanic(t *testing.T) {
+	var g flightGroup
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var once sync.Once
+
+	recovered := make(chan interface{}, 2)
+	call := func() {
+		defer func() { recovered <- recover() }()
+
+		g.do(context.Background(), "a", func(ctx context.Context) (interface{}, error) {
+			once.Do(func() { close(started) })
+			<-release
+			panic("describe failed")
+		})
+	}
+
+	go call()
+	<-started
+	go call()
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-recovered:
+			if r != "describe failed" {
+				t.Errorf("Unexpected panic: %v", r)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Waiting caller was not released after a panic")
+		}
+	}
+}
+
+func TestFlightGroupAllCallersCancel(t *testing.T) {
+	var g flightGroup
+
+	started := make(chan struct{}, 2)
+	cancelled := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	}
+
+	var wg sync.WaitGroup
+	cancels := make([]context.CancelFunc, 2)
+	for i := range cancels {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels[i] = cancel
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := g.do(ctx, "a", fn)
+			if err != context.Canceled {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+
+		if i == 0 {
+			<-started
+		}
+	}
+
+	// Wait until the second caller joined the call, before all callers give up.
+	for {
+		g.mu.Lock()
+		waiters := g.calls["a"].waiters
+		g.mu.Unlock()
+		if waiters == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancels[0]()
+	cancels[1]()
+	wg.Wait()
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("Call was not cancelled after all callers gave up")
+	}
+
+	g.mu.Lock()
+	_, ok := g.calls["a"]
+	g.mu.Unlock()
+	if ok {
+		t.Errorf("Key was not released after all callers gave up")
+	}
+
+	value, err := g.do(context.Background(), "a", func(ctx context.Context) (interface{}, error) { return 1, nil })
+	if err != nil || value != 1 {
+		t.Errorf("Later caller joined the cancelled call: %v, %v", value, err)
+	}
+}
@@ -0,0 +1,33 @@
+package request
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// FlowControl contains the API Priority and Fairness headers of a response. The FlowSchemaUID and PriorityLevelUID
+// identify the flow schema and priority level, which the API server assigned the request to. The RetryAfter is the
+// time in seconds after which a rejected request should be retried, it is zero when the server doesn't send the
+// Retry-After header. Callers can use the values to reduce the request rate, when many requests of the same priority
+// level are rejected.
+type FlowControl struct {
+	FlowSchemaUID    string `json:"flowSchemaUID"`
+	PriorityLevelUID string `json:"priorityLevelUID"`
+	RetryAfter       int64  `json:"retryAfter"`
+}
+
+// parseFlowControl returns the API Priority and Fairness headers from the response header. Headers which are not set,
+// e.g. by API servers without API Priority and Fairness, are returned as zero values.
+func parseFlowControl(header http.Header) FlowControl {
+	flowControl := FlowControl{
+		FlowSchemaUID:    header.Get("X-Kubernetes-PF-FlowSchema-UID"),
+		PriorityLevelUID: header.Get("X-Kubernetes-PF-PriorityLevel-UID"),
+	}
+
+	if retryAfter, err := strconv.ParseInt(strings.TrimSpace(header.Get("Retry-After")), 10, 64); err == nil && retryAfter > 0 {
+		flowControl.RetryAfter = retryAfter
+	}
+
+	return flowControl
+}
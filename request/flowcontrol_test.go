@@ -0,0 +1,45 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFlowControl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Kubernetes-PF-FlowSchema-UID", "flow-schema-uid")
+		w.Header().Set("X-Kubernetes-PF-PriorityLevel-UID", "priority-level-uid")
+
+		if r.URL.Path == "/throttled" {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"kind": "Status", "message": "Too many requests, please try again later.", "reason": "TooManyRequests", "code": 429}`))
+			return
+		}
+	}))
+	defer server.Close()
+
+	resp, err := DoRaw(context.Background(), "GET", server.URL, "", nil)
+	if err != nil {
+		t.Fatalf("Could not start request: %s", err.Error())
+	}
+	resp.Body.Close()
+
+	if flowControl := resp.FlowControl(); flowControl != (FlowControl{FlowSchemaUID: "flow-schema-uid", PriorityLevelUID: "priority-level-uid"}) {
+		t.Errorf("Unexpected flow control: %#v", flowControl)
+	}
+
+	_, err = DoWithOptions("GET", server.URL+"/throttled", "", nil)
+
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if apiError.FlowControl != (FlowControl{FlowSchemaUID: "flow-schema-uid", PriorityLevelUID: "priority-level-uid", RetryAfter: 2}) {
+		t.Errorf("Unexpected flow control: %#v", apiError.FlowControl)
+	}
+}
@@ -0,0 +1,126 @@
+package request
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// githubActionsAudience is the audience of the GitHub Actions OIDC token, which is expected by the GitHub OIDC
+// provider in IAM.
+const githubActionsAudience = "sts.amazonaws.com"
+
+// AWSGetTokenWithGitHubActions returns a bearer token for Kubernetes API requests like AWSGetToken, for a GitHub Actions
+// workflow which authenticates via the GitHub Actions OIDC provider instead of static credentials. The idToken is
+// exchanged for temporary credentials of the role via sts:AssumeRoleWithWebIdentity. If the idToken is empty it is
+// requested from the ACTIONS_ID_TOKEN_REQUEST_URL with the ACTIONS_ID_TOKEN_REQUEST_TOKEN, which requires the
+// "id-token: write" permission for the workflow.
+func AWSGetTokenWithGitHubActions(region, roleARN, clusterID, idToken string) (string, error) {
+	ctx := context.Background()
+
+	if idToken == "" {
+		token, err := githubActionsIDToken(ctx, os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"), os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"))
+		if err != nil {
+			return "", err
+		}
+		idToken = token
+	}
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region), Credentials: credentials.AnonymousCredentials})
+	if err != nil {
+		return "", err
+	}
+
+	sess, err = awsAssumeRoleWithWebIdentity(ctx, sess, roleARN, idToken)
+	if err != nil {
+		return "", err
+	}
+
+	return awsGetToken(sess, clusterID, base64.RawURLEncoding)
+}
+
+// githubActionsIDToken requests an OIDC token for the sts.amazonaws.com audience from the GitHub Actions token
+// endpoint.
+func githubActionsIDToken(ctx context.Context, requestURL, requestToken string) (string, error) {
+	if requestURL == "" || requestToken == "" {
+		return "", errors.New("ACTIONS_ID_TOKEN_REQUEST_URL and ACTIONS_ID_TOKEN_REQUEST_TOKEN are not set, the workflow requires the id-token: write permission")
+	}
+
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("audience", githubActionsAudience)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not get github actions id token: %s", resp.Status)
+	}
+
+	var token struct {
+		Value string `json:"value"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", err
+	}
+
+	if token.Value == "" {
+		return "", errors.New("github actions id token is empty")
+	}
+
+	return token.Value, nil
+}
+
+// awsAssumeRoleWithWebIdentity exchanges the idToken for temporary credentials of the role and returns a copy of the
+// session with these credentials.
+func awsAssumeRoleWithWebIdentity(ctx context.Context, sess *session.Session, roleARN, idToken string) (*session.Session, error) {
+	output, err := sts.New(sess).AssumeRoleWithWebIdentityWithContext(ctx, &sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          aws.String(roleARN),
+		RoleSessionName:  aws.String("kubenav"),
+		WebIdentityToken: aws.String(idToken),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Credentials == nil {
+		return nil, fmt.Errorf("no credentials returned for role %s", roleARN)
+	}
+
+	cred := credentials.NewStaticCredentials(aws.StringValue(output.Credentials.AccessKeyId), aws.StringValue(output.Credentials.SecretAccessKey), aws.StringValue(output.Credentials.SessionToken))
+
+	return sess.Copy(&aws.Config{Credentials: cred}), nil
+}
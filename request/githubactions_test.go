@@ -0,0 +1,67 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubActionsIDToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" || r.URL.Query().Get("audience") != "sts.amazonaws.com" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprintf(w, `{"count": 1, "value": "id-token"}`)
+	}))
+	defer server.Close()
+
+	token, err := githubActionsIDToken(context.Background(), server.URL+"/token?api-version=2.0", "request-token")
+	if err != nil {
+		t.Fatalf("Could not get id token: %s", err.Error())
+	}
+
+	if token != "id-token" {
+		t.Errorf("Unexpected id token: %s", token)
+	}
+
+	_, err = githubActionsIDToken(context.Background(), server.URL+"/token", "invalid-token")
+	if err == nil {
+		t.Errorf("Get id token with invalid request token instead of error")
+	}
+
+	_, err = githubActionsIDToken(context.Background(), "", "")
+	if err == nil {
+		t.Errorf("Get id token without request url instead of error")
+	}
+}
+
+func TestAWSAssumeRoleWithWebIdentity(t *testing.T) {
+	sess, server := fakeAWSSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") != "AssumeRoleWithWebIdentity" || r.Form.Get("WebIdentityToken") != "id-token" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleWithWebIdentityResult><Credentials><AccessKeyId>ASIAEXAMPLE</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>session-token</SessionToken><Expiration>2030-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleWithWebIdentityResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></AssumeRoleWithWebIdentityResponse>`)
+	}))
+	defer server.Close()
+
+	roleSess, err := awsAssumeRoleWithWebIdentity(context.Background(), sess, "arn:aws:iam::123456789012:role/ci", "id-token")
+	if err != nil {
+		t.Fatalf("Could not assume role: %s", err.Error())
+	}
+
+	cred, err := roleSess.Config.Credentials.Get()
+	if err != nil {
+		t.Fatalf("Could not get credentials: %s", err.Error())
+	}
+
+	if cred.AccessKeyID != "ASIAEXAMPLE" || cred.SecretAccessKey != "secret" || cred.SessionToken != "session-token" {
+		t.Errorf("Unexpected credentials: %#v", cred)
+	}
+}
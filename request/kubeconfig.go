@@ -0,0 +1,268 @@
+package request
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string            `yaml:"name"`
+		Cluster kubeconfigCluster `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string            `yaml:"name"`
+		Context kubeconfigContext `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string         `yaml:"name"`
+		User kubeconfigUser `yaml:"user"`
+	} `yaml:"users"`
+}
+
+type kubeconfigCluster struct {
+	Server                   string `yaml:"server"`
+	CertificateAuthority     string `yaml:"certificate-authority"`
+	CertificateAuthorityData string `yaml:"certificate-authority-data"`
+	InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+}
+
+type kubeconfigContext struct {
+	Cluster string `yaml:"cluster"`
+	User    string `yaml:"user"`
+}
+
+type kubeconfigUser struct {
+	ClientCertificate     string          `yaml:"client-certificate"`
+	ClientCertificateData string          `yaml:"client-certificate-data"`
+	ClientKey             string          `yaml:"client-key"`
+	ClientKeyData         string          `yaml:"client-key-data"`
+	Token                 string          `yaml:"token"`
+	TokenFile             string          `yaml:"tokenFile"`
+	Username              string          `yaml:"username"`
+	Password              string          `yaml:"password"`
+	Exec                  *kubeconfigExec `yaml:"exec"`
+}
+
+type kubeconfigExec struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Command    string   `yaml:"command"`
+	Args       []string `yaml:"args"`
+	Env        []struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	} `yaml:"env"`
+}
+
+type execCredential struct {
+	Status struct {
+		Token                 string `json:"token"`
+		ClientCertificateData string `json:"clientCertificateData"`
+		ClientKeyData         string `json:"clientKeyData"`
+	} `json:"status"`
+}
+
+// DoFromKubeconfig runs the given HTTP request against the cluster of the provided context from a kubeconfig file. If
+// the kubeconfigPath is empty the KUBECONFIG environment variable or the default location ~/.kube/config is used. If
+// the contextName is empty the current context of the kubeconfig file is used. The urlPath is joined with the server
+// of the cluster, e.g. "/api/v1/namespaces". The timeout is selected by the HTTP method from the
+// DefaultOperationTimeouts, like for DoJSON.
+func DoFromKubeconfig(kubeconfigPath, contextName, method, urlPath, body string) (string, error) {
+	if kubeconfigPath == "" {
+		path, err := defaultKubeconfigPath()
+		if err != nil {
+			return "", err
+		}
+		kubeconfigPath = path
+	}
+
+	data, err := ioutil.ReadFile(kubeconfigPath)
+	if err != nil {
+		return "", err
+	}
+
+	var config kubeconfig
+	err = yaml.Unmarshal(data, &config)
+	if err != nil {
+		return "", err
+	}
+
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+
+	cluster, user, err := config.resolve(contextName)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Dir(kubeconfigPath)
+
+	certificateAuthorityData, err := kubeconfigData(cluster.CertificateAuthorityData, cluster.CertificateAuthority, dir)
+	if err != nil {
+		return "", err
+	}
+
+	clientCertificateData, err := kubeconfigData(user.ClientCertificateData, user.ClientCertificate, dir)
+	if err != nil {
+		return "", err
+	}
+
+	clientKeyData, err := kubeconfigData(user.ClientKeyData, user.ClientKey, dir)
+	if err != nil {
+		return "", err
+	}
+
+	token := user.Token
+	if token == "" && user.TokenFile != "" {
+		tokenData, err := ioutil.ReadFile(resolvePath(user.TokenFile, dir))
+		if err != nil {
+			return "", err
+		}
+		token = strings.TrimSpace(string(tokenData))
+	}
+
+	if user.Exec != nil {
+		credential, err := user.Exec.run()
+		if err != nil {
+			return "", err
+		}
+
+		if credential.Status.Token != "" {
+			token = credential.Status.Token
+		}
+
+		if credential.Status.ClientCertificateData != "" && credential.Status.ClientKeyData != "" {
+			clientCertificateData = credential.Status.ClientCertificateData
+			clientKeyData = credential.Status.ClientKeyData
+		}
+	}
+
+	url, err := JoinURL(cluster.Server, urlPath)
+	if err != nil {
+		return "", err
+	}
+
+	options := &Options{
+		CertificateAuthorityData: certificateAuthorityData,
+		ClientCertificateData:    clientCertificateData,
+		ClientKeyData:            clientKeyData,
+		Token:                    token,
+		Username:                 user.Username,
+		Password:                 user.Password,
+		InsecureSkipTLSVerify:    cluster.InsecureSkipTLSVerify,
+		OperationTimeouts:        DefaultOperationTimeouts(),
+	}
+
+	return DoWithOptions(method, url, body, operationOptions(options, methodOperation(method)))
+}
+
+// defaultKubeconfigPath returns the first file from the KUBECONFIG environment variable or ~/.kube/config.
+func defaultKubeconfigPath() (string, error) {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)[0], nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// resolve returns the cluster and user for the context with the given name.
+func (c kubeconfig) resolve(contextName string) (kubeconfigCluster, kubeconfigUser, error) {
+	for _, context := range c.Contexts {
+		if context.Name != contextName {
+			continue
+		}
+
+		var user kubeconfigUser
+		for _, u := range c.Users {
+			if u.Name == context.Context.User {
+				user = u.User
+			}
+		}
+
+		for _, cluster := range c.Clusters {
+			if cluster.Name == context.Context.Cluster {
+				return cluster.Cluster, user, nil
+			}
+		}
+
+		return kubeconfigCluster{}, kubeconfigUser{}, fmt.Errorf("cluster %q for context %q not found", context.Context.Cluster, contextName)
+	}
+
+	return kubeconfigCluster{}, kubeconfigUser{}, fmt.Errorf("context %q not found", contextName)
+}
+
+// kubeconfigData returns the decoded base64 data or, when no data is set, the content of the referenced file.
+func kubeconfigData(data, file, dir string) (string, error) {
+	if data != "" {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return "", err
+		}
+
+		return string(decoded), nil
+	}
+
+	if file != "" {
+		content, err := ioutil.ReadFile(resolvePath(file, dir))
+		if err != nil {
+			return "", err
+		}
+
+		return string(content), nil
+	}
+
+	return "", nil
+}
+
+// resolvePath returns the path of a file referenced in a kubeconfig, relative paths are resolved against the
+// directory of the kubeconfig file.
+func resolvePath(path, dir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
+// run executes the exec credential plugin and returns the parsed ExecCredential.
+func (e *kubeconfigExec) run() (*execCredential, error) {
+	cmd := exec.Command(e.Command, e.Args...)
+	cmd.Env = os.Environ()
+	for _, env := range e.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("KUBERNETES_EXEC_INFO={\"apiVersion\": \"%s\", \"kind\": \"ExecCredential\", \"spec\": {\"interactive\": false}}", e.APIVersion))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("exec plugin %s failed: %s: %s", e.Command, err.Error(), strings.TrimSpace(stderr.String()))
+	}
+
+	var credential execCredential
+	err = json.Unmarshal(stdout.Bytes(), &credential)
+	if err != nil {
+		return nil, err
+	}
+
+	return &credential, nil
+}
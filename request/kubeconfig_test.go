@@ -0,0 +1,82 @@
+package request
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDoFromKubeconfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-b" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprintf(w, `{"path": "%s"}`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatalf("Could not create temporary directory: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "token"), []byte("token-b\n"), 0600)
+	if err != nil {
+		t.Fatalf("Could not write token file: %s", err.Error())
+	}
+
+	kubeconfigPath := filepath.Join(dir, "config")
+	err = ioutil.WriteFile(kubeconfigPath, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: a
+clusters:
+- name: cluster
+  cluster:
+    server: %s/
+contexts:
+- name: a
+  context:
+    cluster: cluster
+    user: a
+- name: b
+  context:
+    cluster: cluster
+    user: b
+users:
+- name: a
+  user:
+    token: token-a
+- name: b
+  user:
+    tokenFile: token
+`, server.URL)), 0600)
+	if err != nil {
+		t.Fatalf("Could not write kubeconfig: %s", err.Error())
+	}
+
+	data, err := DoFromKubeconfig(kubeconfigPath, "b", "GET", "/api/v1/namespaces", "")
+	if err != nil {
+		t.Errorf("Could not get namespaces: %s", err.Error())
+	}
+
+	if data != `{"path": "/api/v1/namespaces"}` {
+		t.Errorf("Unexpected response: %s", data)
+	}
+
+	_, err = DoFromKubeconfig(kubeconfigPath, "", "GET", "/api/v1/namespaces", "")
+	if err == nil {
+		t.Errorf("Get namespaces with the token of the current context instead of unauthorized error")
+	}
+
+	_, err = DoFromKubeconfig(kubeconfigPath, "nonexisting-context", "GET", "/api/v1/namespaces", "")
+	if err == nil {
+		t.Errorf("Get namespaces for nonexisting context instead of error")
+	}
+}
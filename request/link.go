@@ -0,0 +1,231 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ListLinkPages requests the given url and follows the Link headers (RFC 8288) with rel="next" until the last page,
+// for aggregated and extension API servers which don't use the continue token of Kubernetes. The pages must be JSON
+// arrays or objects with an items array. The items of all pages are returned as one JSON array or, for objects, as
+// the first page with the items of all pages. Next links to another server are rejected, because the credentials from
+// the options are sent with each page.
+func ListLinkPages(url string, options *Options) (string, error) {
+	return ListLinkPagesContext(context.Background(), url, options)
+}
+
+// ListLinkPagesContext lists all pages like ListLinkPages. The deadline of the context bounds the requests for all
+// pages together.
+func ListLinkPagesContext(ctx context.Context, url string, options *Options) (string, error) {
+	options = operationOptions(options, operationList)
+
+	var first map[string]json.RawMessage
+	var items []json.RawMessage
+
+	visited := map[string]bool{}
+
+	for url != "" {
+		if visited[url] {
+			return "", fmt.Errorf("pagination loop: %s was already requested", url)
+		}
+		visited[url] = true
+
+		resp, err := do(ctx, "GET", url, "", options, false)
+		if err != nil {
+			return "", err
+		}
+
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", err
+		}
+
+		var page interface{}
+		err = json.Unmarshal(data, &page)
+		if err != nil {
+			return "", err
+		}
+
+		if _, ok := page.([]interface{}); ok {
+			var pageItems []json.RawMessage
+			err = json.Unmarshal(data, &pageItems)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, pageItems...)
+		} else {
+			var object map[string]json.RawMessage
+			err = json.Unmarshal(data, &object)
+			if err != nil {
+				return "", err
+			}
+
+			var pageItems []json.RawMessage
+			if object["items"] != nil {
+				err = json.Unmarshal(object["items"], &pageItems)
+				if err != nil {
+					return "", err
+				}
+			}
+			items = append(items, pageItems...)
+
+			if first == nil {
+				first = object
+			}
+		}
+
+		url, err = nextLink(resp.Request.URL, resp.Header)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if items == nil {
+		items = []json.RawMessage{}
+	}
+
+	var result interface{} = items
+	if first != nil {
+		itemsData, err := json.Marshal(items)
+		if err != nil {
+			return "", err
+		}
+
+		first["items"] = itemsData
+		result = first
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// nextLink returns the target of the Link header with rel="next", resolved against the url of the request. If the
+// header doesn't contain a next link an empty string is returned.
+func nextLink(requestURL *url.URL, header http.Header) (string, error) {
+	for _, value := range header["Link"] {
+		links, err := parseLinks(value)
+		if err != nil {
+			return "", err
+		}
+
+		for _, link := range links {
+			if !link.hasRel("next") {
+				continue
+			}
+
+			next, err := requestURL.Parse(link.target)
+			if err != nil {
+				return "", err
+			}
+
+			// The credentials are sent with each page, so that the next link must not point to another server.
+			if next.Scheme != requestURL.Scheme || next.Host != requestURL.Host {
+				return "", fmt.Errorf("next link %s points to another server than %s", next, requestURL.Host)
+			}
+
+			return next.String(), nil
+		}
+	}
+
+	return "", nil
+}
+
+// link is a single link of a Link header with its target and the values of its rel parameter.
+type link struct {
+	target string
+	rels   []string
+}
+
+func (l link) hasRel(rel string) bool {
+	for _, r := range l.rels {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseLinks parses the links of a Link header value. The target between "<" and ">" is read before the parameters are
+// split, because it can contain commas and semicolons, e.g. in a label selector like "app in (a,b)". Quoted parameter
+// values can contain commas and semicolons too.
+func parseLinks(value string) ([]link, error) {
+	var links []link
+
+	for {
+		value = strings.TrimLeft(value, " \t,")
+		if value == "" {
+			return links, nil
+		}
+
+		if value[0] != '<' {
+			return nil, fmt.Errorf("invalid link header: expected \"<\" at %q", value)
+		}
+
+		end := strings.IndexByte(value, '>')
+		if end == -1 {
+			return nil, fmt.Errorf("invalid link header: missing \">\" in %q", value)
+		}
+
+		l := link{target: value[1:end]}
+		value = value[end+1:]
+
+		// The parameters end at the first comma outside of a quoted value.
+		for {
+			value = strings.TrimLeft(value, " \t")
+			if value == "" || value[0] == ',' {
+				break
+			}
+
+			if value[0] != ';' {
+				return nil, fmt.Errorf("invalid link header: expected \";\" at %q", value)
+			}
+			value = strings.TrimLeft(value[1:], " \t")
+
+			var name, paramValue string
+			name, value = splitToken(value)
+			value = strings.TrimLeft(value, " \t")
+
+			if value != "" && value[0] == '=' {
+				value = strings.TrimLeft(value[1:], " \t")
+
+				if value != "" && value[0] == '"' {
+					closing := strings.IndexByte(value[1:], '"')
+					if closing == -1 {
+						return nil, fmt.Errorf("invalid link header: unterminated quoted value in %q", value)
+					}
+					paramValue = value[1 : closing+1]
+					value = value[closing+2:]
+				} else {
+					paramValue, value = splitToken(value)
+				}
+			}
+
+			if strings.EqualFold(name, "rel") {
+				l.rels = append(l.rels, strings.Fields(paramValue)...)
+			}
+		}
+
+		links = append(links, l)
+	}
+}
+
+// splitToken returns the token at the start of s until the next separator of a link parameter and the rest of s.
+func splitToken(s string) (string, string) {
+	end := strings.IndexAny(s, " \t=;,")
+	if end == -1 {
+		return s, ""
+	}
+
+	return s[:end], s[end:]
+}
@@ -0,0 +1,95 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestListLinkPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path + "?" + r.URL.RawQuery {
+		case "/apis/metrics/v1/nodes?":
+			w.Header().Add("Link", `</apis/metrics/v1/nodes?page=2>; rel="next", </apis/metrics/v1/nodes?page=3>; rel="last"`)
+			fmt.Fprintf(w, `{"kind": "NodeMetricsList", "items": [{"name": "node-1"}]}`)
+		case "/apis/metrics/v1/nodes?page=2":
+			w.Header().Add("Link", `<?page=3>; rel="next"`)
+			fmt.Fprintf(w, `{"kind": "NodeMetricsList", "items": [{"name": "node-2"}]}`)
+		case "/apis/metrics/v1/nodes?page=3":
+			fmt.Fprintf(w, `{"kind": "NodeMetricsList", "items": [{"name": "node-3"}]}`)
+		case "/selector?labelSelector=app+in+%28a%2Cb%29":
+			w.Header().Add("Link", `</selector?labelSelector=app+in+(a,b)&page=2>; rel="next"`)
+			fmt.Fprintf(w, `[1]`)
+		case "/selector?labelSelector=app+in+(a,b)&page=2":
+			fmt.Fprintf(w, `[2]`)
+		case "/array?":
+			w.Header().Add("Link", `</array?page=2>; rel=next`)
+			fmt.Fprintf(w, `[1, 2]`)
+		case "/array?page=2":
+			fmt.Fprintf(w, `[3]`)
+		case "/loop?":
+			w.Header().Add("Link", `</loop>; rel="next"`)
+			fmt.Fprintf(w, `[]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	data, err := ListLinkPages(server.URL+"/apis/metrics/v1/nodes", nil)
+	if err != nil {
+		t.Fatalf("Could not list pages: %s", err.Error())
+	}
+
+	if data != `{"items":[{"name":"node-1"},{"name":"node-2"},{"name":"node-3"}],"kind":"NodeMetricsList"}` {
+		t.Errorf("Unexpected objects: %s", data)
+	}
+
+	data, err = ListLinkPages(server.URL+"/array", nil)
+	if err != nil {
+		t.Fatalf("Could not list pages: %s", err.Error())
+	}
+
+	if data != `[1,2,3]` {
+		t.Errorf("Unexpected array: %s", data)
+	}
+
+	data, err = ListLinkPages(server.URL+"/selector?labelSelector=app+in+%28a%2Cb%29", nil)
+	if err != nil {
+		t.Fatalf("Could not list pages: %s", err.Error())
+	}
+
+	if data != `[1,2]` {
+		t.Errorf("Unexpected pages for a next link with a comma: %s", data)
+	}
+
+	_, err = ListLinkPages(server.URL+"/loop", nil)
+	if err == nil {
+		t.Errorf("List pages with loop instead of error")
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	requestURL, _ := url.Parse("https://cluster.example.com/apis/metrics/v1/nodes")
+
+	for _, tc := range []struct {
+		link     string
+		expected string
+		err      bool
+	}{
+		{link: `<https://cluster.example.com/apis/metrics/v1/nodes?page=2>; rel="next"`, expected: "https://cluster.example.com/apis/metrics/v1/nodes?page=2"},
+		{link: `<?page=1>; rel="prev", <?page=3>; title="next"; rel="last next"`, expected: "https://cluster.example.com/apis/metrics/v1/nodes?page=3"},
+		{link: `<?page=1>; rel="prev"`, expected: ""},
+		{link: `<https://other.example.com/nodes?page=2>; rel="next"`, err: true},
+		{link: `<?labelSelector=app in (a,b)&page=2>; rel="next"`, expected: "https://cluster.example.com/apis/metrics/v1/nodes?labelSelector=app in (a,b)&page=2"},
+		{link: `<?page=1;a,b>; title="first, or; last"; rel=prev, <?page=3>; rel=next`, expected: "https://cluster.example.com/apis/metrics/v1/nodes?page=3"},
+		{link: `?page=2; rel="next"`, err: true},
+	} {
+		next, err := nextLink(requestURL, http.Header{"Link": []string{tc.link}})
+		if (err != nil) != tc.err || next != tc.expected {
+			t.Errorf("Unexpected next link for %s: %s, %v", tc.link, next, err)
+		}
+	}
+}
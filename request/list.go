@@ -0,0 +1,53 @@
+package request
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// ListOptions are the query parameters for a list request.
+type ListOptions struct {
+	LabelSelector   string
+	FieldSelector   string
+	Limit           int64
+	Continue        string
+	ResourceVersion string
+}
+
+// query returns the encoded query string for the list options.
+func (o ListOptions) query() string {
+	values := url.Values{}
+
+	if o.LabelSelector != "" {
+		values.Set("labelSelector", o.LabelSelector)
+	}
+	if o.FieldSelector != "" {
+		values.Set("fieldSelector", o.FieldSelector)
+	}
+	if o.Limit > 0 {
+		values.Set("limit", strconv.FormatInt(o.Limit, 10))
+	}
+	if o.Continue != "" {
+		values.Set("continue", o.Continue)
+	}
+	if o.ResourceVersion != "" {
+		values.Set("resourceVersion", o.ResourceVersion)
+	}
+
+	return values.Encode()
+}
+
+// ListAllNamespaces lists the given resource across all namespaces, e.g. all pods via "/api/v1/pods" instead of
+// "/api/v1/namespaces/{namespace}/pods". The base is the url of the API server.
+func ListAllNamespaces(base string, gvr GroupVersionResource, opts ListOptions, options *Options) (string, error) {
+	listURL, err := ResourceURL(base, gvr, "", "")
+	if err != nil {
+		return "", err
+	}
+
+	if query := opts.query(); query != "" {
+		listURL = listURL + "?" + query
+	}
+
+	return DoWithOptions("GET", listURL, "", operationOptions(options, operationList))
+}
@@ -0,0 +1,32 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListAllNamespaces(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RequestURI()))
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		gvr      GroupVersionResource
+		opts     ListOptions
+		expected string
+	}{
+		{gvr: GroupVersionResource{Version: "v1", Resource: "pods"}, expected: "/api/v1/pods"},
+		{gvr: GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, opts: ListOptions{LabelSelector: "app=nginx", Limit: 10}, expected: "/apis/apps/v1/deployments?labelSelector=app%3Dnginx&limit=10"},
+	} {
+		data, err := ListAllNamespaces(server.URL, tc.gvr, tc.opts, nil)
+		if err != nil {
+			t.Errorf("Could not list %v: %s", tc.gvr, err.Error())
+		}
+
+		if data != tc.expected {
+			t.Errorf("Unexpected request uri for %v: %s", tc.gvr, data)
+		}
+	}
+}
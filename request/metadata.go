@@ -0,0 +1,52 @@
+package request
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+type metadataKey struct{}
+
+// WithMetadata returns a copy of the context with the given key and value added to the request metadata. The metadata
+// is added to the log messages for requests which are sent with the context, so that they can be correlated with the
+// tenant or operation which triggered them. The MetricsMiddleware adds the metadata of its label keys to the metrics.
+// No metadata is added by default, so that high cardinality values like user names only show up when a caller adds
+// them explicitly.
+func WithMetadata(ctx context.Context, key, value string) context.Context {
+	metadata := map[string]string{}
+	for k, v := range Metadata(ctx) {
+		metadata[k] = v
+	}
+	metadata[key] = value
+
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// Metadata returns the request metadata of the context, which was added via WithMetadata. The returned map must not be
+// modified.
+func Metadata(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+
+	metadata, _ := ctx.Value(metadataKey{}).(map[string]string)
+	return metadata
+}
+
+// formatMetadata returns the metadata of the context as sorted key=value pairs for log messages. If the context
+// doesn't contain metadata an empty string is returned.
+func formatMetadata(ctx context.Context) string {
+	metadata := Metadata(ctx)
+	if len(metadata) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,23 @@
+package request
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithMetadata(t *testing.T) {
+	parent := WithMetadata(context.Background(), "tenant", "payments")
+	child := WithMetadata(parent, "operation", "list-pods")
+
+	if metadata := formatMetadata(child); metadata != "operation=list-pods tenant=payments" {
+		t.Errorf("Unexpected metadata: %s", metadata)
+	}
+
+	if metadata := formatMetadata(parent); metadata != "tenant=payments" {
+		t.Errorf("Metadata of the parent context was modified: %s", metadata)
+	}
+
+	if metadata := formatMetadata(context.Background()); metadata != "" {
+		t.Errorf("Unexpected metadata for context without metadata: %s", metadata)
+	}
+}
@@ -0,0 +1,327 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// RoundTripFunc sends a single HTTP request and returns the response, like the RoundTrip method of an
+// http.RoundTripper.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the RoundTripFunc of a request, to add behavior before the request is sent or after the response is
+// received. A middleware must call next to send the request.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// chainMiddlewares returns a RoundTripFunc, which calls the middlewares in the given order before the transport. The
+// first middleware is the outermost one, so that it sees the request first and the response last.
+func chainMiddlewares(transport http.RoundTripper, middlewares []Middleware) RoundTripFunc {
+	next := RoundTripFunc(transport.RoundTrip)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		next = middlewares[i](next)
+	}
+
+	return next
+}
+
+// Logger is used by the LoggingMiddleware to log the requests and by the AWS helpers to log the retries of AWS API
+// calls.
+type Logger interface {
+	Log(message string)
+}
+
+// LoggingMiddleware logs the method, url, status and duration of each request. The metadata added to the context of the
+// request via WithMetadata is appended to the messages. Headers are not logged, so that no credentials are written to
+// the log.
+func LoggingMiddleware(logger Logger) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			duration := time.Since(start).Round(time.Millisecond)
+
+			var message string
+			if err != nil {
+				message = fmt.Sprintf("%s %s failed after %s: %s", req.Method, redactURL(req.URL), duration, err.Error())
+			} else {
+				message = fmt.Sprintf("%s %s returned %s in %s", req.Method, redactURL(req.URL), resp.Status, duration)
+			}
+
+			if metadata := formatMetadata(req.Context()); metadata != "" {
+				message = message + " " + metadata
+			}
+
+			logger.Log(message)
+
+			return resp, err
+		}
+	}
+}
+
+// redactURL returns the url without the password of the user info, so that it can be logged.
+func redactURL(u *url.URL) string {
+	if u.User == nil {
+		return u.String()
+	}
+	if _, ok := u.User.Password(); !ok {
+		return u.String()
+	}
+
+	redacted := *u
+	redacted.User = url.UserPassword(u.User.Username(), "xxxxx")
+	return redacted.String()
+}
+
+// RetryAttempt is a single attempt of a request, which was sent via the RetryMiddleware. The StatusCode is zero when
+// the attempt failed with the connection error Err. Duration is the time until the response headers were received and
+// Backoff the time waited before the next attempt, which is zero for the last attempt.
+type RetryAttempt struct {
+	Attempt    int
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+	Backoff    time.Duration
+}
+
+// RetryError is returned when a request, which was sent via the RetryMiddleware, failed with a connection error. It
+// contains the trace of all attempts, the error of the last attempt is returned by Unwrap.
+type RetryError struct {
+	Attempts []RetryAttempt
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+type retryTraceKey struct{}
+
+// retryTrace collects the attempts of a request. A new trace is added to the context of each request in do, so that
+// the attempts can be attached to the response or error.
+type retryTrace struct {
+	attempts []RetryAttempt
+}
+
+func withRetryTrace(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryTraceKey{}, &retryTrace{})
+}
+
+// retryAttempts returns the attempts recorded in the context, or nil if the request wasn't sent via the
+// RetryMiddleware.
+func retryAttempts(ctx context.Context) []RetryAttempt {
+	trace, ok := ctx.Value(retryTraceKey{}).(*retryTrace)
+	if !ok {
+		return nil
+	}
+
+	return trace.attempts
+}
+
+// retryError wraps the error with the attempts recorded in the context. If no attempts were recorded the error is
+// returned unchanged.
+func retryError(ctx context.Context, err error) error {
+	attempts := retryAttempts(ctx)
+	if attempts == nil {
+		return err
+	}
+
+	return &RetryError{Attempts: attempts, Err: err}
+}
+
+// RetryMiddleware retries requests which failed because of a connection error, or which were rejected with a 429,
+// 502, 503 or 504 status code. Only idempotent requests (GET, HEAD, OPTIONS, PUT and DELETE) are retried, so that an
+// object isn't created twice. The backoff is doubled for each retry and the Retry-After header of the server is used
+// when it is longer. All attempts are recorded and attached to the Attempts of the APIError, RetryError or Response.
+func RetryMiddleware(maxRetries int, backoff time.Duration) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			trace, _ := req.Context().Value(retryTraceKey{}).(*retryTrace)
+
+			for attempt := 0; ; attempt++ {
+				attemptReq := req
+				if attempt > 0 && req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, err
+					}
+
+					attemptReq = req.Clone(req.Context())
+					attemptReq.Body = body
+				}
+
+				start := time.Now()
+				resp, err := next(attemptReq)
+
+				record := RetryAttempt{Attempt: attempt + 1, Err: err, Duration: time.Since(start)}
+				if resp != nil {
+					record.StatusCode = resp.StatusCode
+				}
+
+				if attempt >= maxRetries || !idempotentMethod(req.Method) || !retryableResponse(resp, err) {
+					if trace != nil {
+						trace.attempts = append(trace.attempts, record)
+					}
+					return resp, err
+				}
+
+				wait := backoff * time.Duration(math.Pow(2, float64(attempt)))
+				if resp != nil {
+					if retryAfter := time.Duration(parseFlowControl(resp.Header).RetryAfter) * time.Second; retryAfter > wait {
+						wait = retryAfter
+					}
+					resp.Body.Close()
+				}
+
+				record.Backoff = wait
+				if trace != nil {
+					trace.attempts = append(trace.attempts, record)
+				}
+
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-req.Context().Done():
+					timer.Stop()
+					return nil, req.Context().Err()
+				}
+			}
+		}
+	}
+}
+
+// idempotentMethod returns true for the HTTP methods, which can be retried without changing the result.
+func idempotentMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+
+	return false
+}
+
+// retryableResponse returns true when the request failed because of a connection error or a status code, which
+// indicates that the server is temporarily not available.
+func retryableResponse(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// RateLimitMiddleware limits the rate of the requests, which are sent with the returned middleware, to qps requests
+// per second. The middleware must be created once and shared by all requests which should be limited together,
+// requests wait until they are allowed to be sent or their context is done. When qps isn't positive or so small that
+// the interval between two requests overflows, all requests fail with ErrInvalidRateLimit, because the limit would be
+// disabled silently otherwise.
+func RateLimitMiddleware(qps float64) Middleware {
+	if !(qps > 0) || float64(time.Second)/qps > math.MaxInt64 {
+		err := fmt.Errorf("%w: %v requests per second", ErrInvalidRateLimit, qps)
+
+		return func(nextRoundTrip RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				return nil, err
+			}
+		}
+	}
+
+	interval := time.Duration(float64(time.Second) / qps)
+
+	var mu sync.Mutex
+	var next time.Time
+
+	return func(nextRoundTrip RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			now := time.Now()
+			if next.Before(now) {
+				next = now
+			}
+			wait := next.Sub(now)
+			next = next.Add(interval)
+			mu.Unlock()
+
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-req.Context().Done():
+					timer.Stop()
+					return nil, req.Context().Err()
+				}
+			}
+
+			return nextRoundTrip(req)
+		}
+	}
+}
+
+// RequestMetric contains the measurements of a single request, which was sent via the MetricsMiddleware. The
+// StatusCode is zero when the request failed with the connection error Err and the Duration is the time until the
+// response headers were received. Only the host of the url is included, so that the metrics don't get a high
+// cardinality through the paths of the resources. The Labels contain the request metadata of the label keys, which
+// were passed to the MetricsMiddleware.
+type RequestMetric struct {
+	Method     string
+	Host       string
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+	Labels     map[string]string
+}
+
+// MetricsRecorder is used by the MetricsMiddleware to record the metrics of the requests, e.g. as Prometheus counters
+// and histograms.
+type MetricsRecorder interface {
+	Observe(metric RequestMetric)
+}
+
+// MetricsMiddleware records the method, host, status code and duration of each request. When it is combined with the
+// RetryMiddleware, it records each attempt if it is added after the RetryMiddleware and the whole request otherwise.
+// The request metadata added via WithMetadata is only recorded for the given label keys, so that the cardinality of
+// the metrics stays under the control of the caller. Keys without a value in the metadata are omitted.
+func MetricsMiddleware(recorder MetricsRecorder, labelKeys ...string) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+
+			metric := RequestMetric{Method: req.Method, Host: req.URL.Host, Err: err, Duration: time.Since(start)}
+			if resp != nil {
+				metric.StatusCode = resp.StatusCode
+			}
+
+			metadata := Metadata(req.Context())
+			for _, key := range labelKeys {
+				if value, ok := metadata[key]; ok {
+					if metric.Labels == nil {
+						metric.Labels = map[string]string{}
+					}
+					metric.Labels[key] = value
+				}
+			}
+			recorder.Observe(metric)
+
+			return resp, err
+		}
+	}
+}
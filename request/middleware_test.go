@@ -0,0 +1,277 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMiddlewaresOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Order")))
+	}))
+	defer server.Close()
+
+	var order []string
+	middleware := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				req.Header.Add("X-Order", name)
+				resp, err := next(req)
+				order = append(order, name)
+				return resp, err
+			}
+		}
+	}
+
+	data, err := DoWithOptions("GET", server.URL, "", &Options{
+		Middlewares: []Middleware{middleware("first"), middleware("second")},
+	})
+	if err != nil {
+		t.Fatalf("Could not send request: %s", err.Error())
+	}
+
+	if data != "first" {
+		t.Errorf("First middleware didn't see the request first: %s", data)
+	}
+
+	if strings.Join(order, ",") != "second,first" {
+		t.Errorf("First middleware didn't see the response last: %v", order)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	logger := &testLogger{}
+	ctx := WithMetadata(context.Background(), "tenant", "payments")
+
+	_, err := DoContext(ctx, "GET", server.URL+"/api", "", &Options{
+		Token:       "secret",
+		Middlewares: []Middleware{LoggingMiddleware(logger)},
+	})
+	if err != nil {
+		t.Fatalf("Could not send request: %s", err.Error())
+	}
+
+	if len(logger.messages) != 1 {
+		t.Fatalf("Expected one log message, got %v", logger.messages)
+	}
+
+	message := logger.messages[0]
+	if !strings.HasPrefix(message, "GET "+server.URL+"/api returned 200 OK in ") || !strings.HasSuffix(message, " tenant=payments") {
+		t.Errorf("Unexpected log message: %s", message)
+	}
+
+	if strings.Contains(message, "secret") {
+		t.Errorf("Log message contains the token: %s", message)
+	}
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	options := &Options{Middlewares: []Middleware{RetryMiddleware(2, time.Millisecond)}}
+
+	data, err := DoWithOptions("PUT", server.URL, `{"kind":"Pod"}`, options)
+	if err != nil {
+		t.Fatalf("Request was not retried: %s", err.Error())
+	}
+
+	if data != `{"kind":"Pod"}` {
+		t.Errorf("Body was not sent again for the retry: %s", data)
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	_, err = DoWithOptions("POST", server.URL, `{"kind":"Pod"}`, options)
+	if err == nil {
+		t.Errorf("Non-idempotent request was retried")
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected one POST request, got %d", n)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	options := &Options{Middlewares: []Middleware{RateLimitMiddleware(20)}}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := DoWithOptions("GET", server.URL, "", options)
+		if err != nil {
+			t.Fatalf("Could not send request: %s", err.Error())
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Requests were not rate limited: %s", elapsed)
+	}
+}
+
+func TestRetryMiddlewareAttempts(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1)%2 == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	options := &Options{Middlewares: []Middleware{RetryMiddleware(1, time.Millisecond)}}
+
+	resp, err := DoRaw(context.Background(), "GET", server.URL, "", options)
+	if err != nil {
+		t.Fatalf("Request was not retried: %s", err.Error())
+	}
+	resp.Body.Close()
+
+	if len(resp.Attempts) != 2 || resp.Attempts[0].StatusCode != http.StatusTooManyRequests || resp.Attempts[0].Backoff != time.Millisecond || resp.Attempts[1].StatusCode != http.StatusOK || resp.Attempts[1].Backoff != 0 {
+		t.Errorf("Unexpected attempts for the response: %+v", resp.Attempts)
+	}
+
+	options = &Options{Middlewares: []Middleware{RetryMiddleware(0, time.Millisecond)}}
+
+	atomic.StoreInt32(&requests, 0)
+	_, err = DoWithOptions("GET", server.URL, "", options)
+
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("Expected APIError, got %v", err)
+	}
+
+	if len(apiError.Attempts) != 1 || apiError.Attempts[0].Attempt != 1 || apiError.Attempts[0].StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Unexpected attempts for the error: %+v", apiError.Attempts)
+	}
+
+	server.Close()
+
+	options = &Options{Middlewares: []Middleware{RetryMiddleware(2, time.Millisecond)}}
+
+	_, err = DoWithOptions("GET", server.URL, "", options)
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("Expected RetryError, got %v", err)
+	}
+
+	if len(retryErr.Attempts) != 3 || retryErr.Attempts[2].Err == nil || retryErr.Attempts[2].StatusCode != 0 || retryErr.Attempts[1].Backoff != 2*time.Millisecond {
+		t.Errorf("Unexpected attempts for the connection error: %+v", retryErr.Attempts)
+	}
+}
+
+func TestRateLimitMiddlewareInvalid(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+
+	for _, qps := range []float64{0, -1, 1e-30} {
+		_, err := DoWithOptions("GET", server.URL, "", &Options{Middlewares: []Middleware{RateLimitMiddleware(qps)}})
+		if !errors.Is(err, ErrInvalidRateLimit) {
+			t.Errorf("Unexpected error for %v requests per second: %v", qps, err)
+		}
+	}
+
+	if requests := atomic.LoadInt32(&requests); requests != 0 {
+		t.Errorf("%d requests were sent with an invalid rate limit", requests)
+	}
+}
+
+type testMetricsRecorder struct {
+	metrics []RequestMetric
+}
+
+func (r *testMetricsRecorder) Observe(metric RequestMetric) {
+	r.metrics = append(r.metrics, metric)
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	recorder := &testMetricsRecorder{}
+
+	_, err := DoWithOptions("GET", server.URL+"/api/v1/pods", "", &Options{
+		Middlewares: []Middleware{RetryMiddleware(1, time.Millisecond), MetricsMiddleware(recorder)},
+	})
+	if err != nil {
+		t.Fatalf("Could not send request: %s", err.Error())
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	if len(recorder.metrics) != 2 ||
+		recorder.metrics[0].Method != "GET" || recorder.metrics[0].Host != host || recorder.metrics[0].StatusCode != http.StatusServiceUnavailable ||
+		recorder.metrics[1].StatusCode != http.StatusOK {
+		t.Errorf("Unexpected metrics: %+v", recorder.metrics)
+	}
+}
+
+func TestMetricsMiddlewareLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ctx := WithMetadata(context.Background(), "cluster", "dev")
+	ctx = WithMetadata(ctx, "user", "admin")
+
+	recorder := &testMetricsRecorder{}
+	options := &Options{Middlewares: []Middleware{MetricsMiddleware(recorder, "cluster", "namespace")}}
+
+	_, err := DoContext(ctx, "GET", server.URL, "", options)
+	if err != nil {
+		t.Fatalf("Could not send request: %s", err.Error())
+	}
+
+	_, err = DoContext(context.Background(), "GET", server.URL, "", options)
+	if err != nil {
+		t.Fatalf("Could not send request: %s", err.Error())
+	}
+
+	if len(recorder.metrics) != 2 || !reflect.DeepEqual(recorder.metrics[0].Labels, map[string]string{"cluster": "dev"}) || recorder.metrics[1].Labels != nil {
+		t.Errorf("Unexpected labels: %+v", recorder.metrics)
+	}
+
+	recorder = &testMetricsRecorder{}
+	_, err = DoContext(ctx, "GET", server.URL, "", &Options{Middlewares: []Middleware{MetricsMiddleware(recorder)}})
+	if err != nil {
+		t.Fatalf("Could not send request: %s", err.Error())
+	}
+
+	if len(recorder.metrics) != 1 || recorder.metrics[0].Labels != nil {
+		t.Errorf("Metadata was recorded without label keys: %+v", recorder.metrics)
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -16,189 +19,511 @@ import (
 	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2020-01-01/containerservice"
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/adal"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/eks"
-	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/coreos/go-oidc"
 	"golang.org/x/oauth2"
 	"gopkg.in/yaml.v2"
 )
 
+var (
+	// ErrRequestTooLarge is returned when the request body exceeds the MaxRequestBytes option.
+	ErrRequestTooLarge = errors.New("request body is too large")
+	// ErrEmptyResponse is returned for a 2xx response without a body, when the RequireBody option is set.
+	ErrEmptyResponse = errors.New("response body is empty")
+	// ErrInvalidRateLimit is returned for all requests sent with a RateLimitMiddleware with an invalid rate.
+	ErrInvalidRateLimit = errors.New("invalid rate limit")
+)
+
+// APIError is returned for all responses with a non-2xx status code. The fields are decoded from the Status object of
+// the API server. When the body isn't a Status object, e.g. for a 413 from a proxy, the Message is the HTTP status and
+// the Code the status code. The raw response body is available in Body and the API Priority and Fairness headers, e.g.
+// for a 429, in FlowControl.
 type APIError struct {
-	Kind       string `json:"kind"`
-	APIVersion string `json:"apiVersion"`
-	Status     string `json:"status"`
-	Message    string `json:"message"`
-	Reason     string `json:"reason"`
-	Code       int    `json:"code"`
+	Kind        string      `json:"kind"`
+	APIVersion  string      `json:"apiVersion"`
+	Status      string      `json:"status"`
+	Message     string      `json:"message"`
+	Reason      string      `json:"reason"`
+	Code        int         `json:"code"`
+	Body        string      `json:"-"`
+	FlowControl FlowControl `json:"-"`
+	// Attempts contains the trace of all attempts, when the request was sent via the RetryMiddleware.
+	Attempts []RetryAttempt `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// RedirectError is returned for a 3xx response, when redirects are disabled via the DisableRedirects option. The
+// Location is the target of the redirect and Body the raw response body.
+type RedirectError struct {
+	StatusCode int
+	Status     string
+	Location   string
+	Body       string
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("redirected with %s to %s", e.Status, e.Location)
+}
+
+// Options contains the settings for a request. The options are used by DoWithOptions and DoRaw, Do passes its
+// arguments as options.
+type Options struct {
+	CertificateAuthorityData string
+	ClientCertificateData    string
+	ClientKeyData            string
+	Token                    string
+	Username                 string
+	Password                 string
+	InsecureSkipTLSVerify    bool
+	// ClientKeyPassphrase is the passphrase for an encrypted ClientKeyData. Only the legacy PEM encryption with the
+	// "Proc-Type: 4,ENCRYPTED" header is supported, which is insecure by design and should only be used when the key
+	// can't be stored unencrypted.
+	ClientKeyPassphrase string
+	// Server is the base url of the API server. If it is set the url of a request can be a path, which is joined with
+	// the server via JoinURL.
+	Server string
+	// Timeout is the timeout for the request in seconds. For requests started with DoRaw the timeout is only applied
+	// until the response headers are received, so that a slow but progressing stream isn't cut off.
+	Timeout int64
+	// TLSHandshakeTimeout is the maximum time in seconds to wait for the TLS handshake, independent of the Timeout. If
+	// the value is zero the handshake is only limited by the Timeout.
+	TLSHandshakeTimeout int64
+	// IdleTimeout is the maximum time in seconds to wait for a single read from or write to the connection. It
+	// detects connections which stall in the middle of a transfer, before the Timeout is exceeded. If the value is
+	// zero, reads and writes are only limited by the Timeout.
+	IdleTimeout int64
+	// ClockSkewTolerance is the time in seconds by which the server certificate can be expired or not yet valid, for
+	// environments where the clocks aren't synchronized properly. All other checks of the certificate are unchanged. If
+	// the value is zero the validity period is checked strictly.
+	ClockSkewTolerance int64
+	// VerifyDigest verifies the response body against the Content-Digest (RFC 9530) or Digest (RFC 3230) header, when
+	// the server sends one of them. If the body doesn't match ErrDigestMismatch is returned. The option is used by all
+	// functions which return the response body, i.e. DoWithOptions, DoContext and DoJSON.
+	VerifyDigest bool
+	// MaxRequestBytes is the maximum size of the request body. If the body is larger ErrRequestTooLarge is returned
+	// without sending the request. If the value is zero the size isn't limited.
+	MaxRequestBytes int64
+	// RequireBody returns ErrEmptyResponse when the body of a 2xx response is empty or only contains whitespace, for
+	// requests which must always return an object. By default an empty body is returned without an error. The option
+	// is used by DoWithOptions, DoContext and DoJSON.
+	RequireBody bool
+	// ExpectContinue sends requests with a body with the "Expect: 100-continue" header. The body is only sent after the
+	// server accepted the request, so that an early rejection like a 413 from an admission webhook or proxy is returned
+	// as APIError instead of an error for the interrupted write of the body.
+	ExpectContinue bool
+	// DisableRedirects disables following of redirects. A 3xx response is returned as RedirectError, which contains the
+	// Location of the redirect, instead of an APIError.
+	DisableRedirects bool
+	// Headers are additional headers for the request. They override the headers which are set by default, like Accept,
+	// Content-Type and the Authorization for the Token. The Impersonate-Group and Impersonate-Extra-* headers can be
+	// repeated, so that their values are appended instead.
+	Headers http.Header
+	// UpgradeToHTTPS handles http urls for servers, which redirect to https. The request is first sent without the
+	// credentials and the Authorization header. When the server redirects to https on the same host, the request is
+	// sent with the credentials to the https url. Otherwise the response of the request without credentials is used,
+	// so that credentials are never sent over an unencrypted connection.
+	UpgradeToHTTPS bool
+	// FallbackToken, FallbackUsername and FallbackPassword are secondary credentials. If the API server responds with a
+	// 401 for the Token, Username and Password, the request is retried once with the fallback credentials.
+	FallbackToken    string
+	FallbackUsername string
+	FallbackPassword string
+	// Middlewares wrap the sending of the request, e.g. with the LoggingMiddleware, RetryMiddleware or
+	// RateLimitMiddleware. The first middleware is the outermost one. The middlewares are called for each request
+	// which is sent to the server, including the requests for redirects. If the value is nil the request is sent
+	// directly.
+	Middlewares []Middleware
+	// OperationTimeouts are the default timeouts of the typed helpers like ListAllNamespaces, Watch and Delete. They
+	// are only used when the Timeout is zero, so that the Timeout overrides them for a single call. If the value is nil
+	// the typed helpers use the Timeout, like all other requests.
+	OperationTimeouts *OperationTimeouts
 }
 
 // Do runs the given HTTP request.
 func Do(method, url, body, certificateAuthorityData, clientCertificateData, clientKeyData, token, username, password string, insecureSkipTLSVerify bool, timeout int64) (string, error) {
-	var tlsConfig *tls.Config
-	var err error
+	return DoWithOptions(method, url, body, &Options{
+		CertificateAuthorityData: certificateAuthorityData,
+		ClientCertificateData:    clientCertificateData,
+		ClientKeyData:            clientKeyData,
+		Token:                    token,
+		Username:                 username,
+		Password:                 password,
+		InsecureSkipTLSVerify:    insecureSkipTLSVerify,
+		Timeout:                  timeout,
+	})
+}
+
+// DoWithOptions runs the given HTTP request with the provided options.
+func DoWithOptions(method, url, body string, options *Options) (string, error) {
+	return DoContext(context.Background(), method, url, body, options)
+}
+
+// DoContext runs the given HTTP request with the provided options. The request can be cancelled via the context. When
+// the context is cancelled while the body is read, the data received so far is returned together with the error of
+// the context.
+func DoContext(ctx context.Context, method, url, body string, options *Options) (string, error) {
+	_, respBody, err := doRead(ctx, method, url, body, options)
+	return string(respBody), err
+}
 
-	tlsConfig, err = httpClientForRootCAs(certificateAuthorityData, clientCertificateData, clientKeyData, insecureSkipTLSVerify)
+// doRead sends the HTTP request, reads the response body and applies the checks of the VerifyDigest and RequireBody
+// options. When reading the body fails, the data received so far is returned together with the error.
+func doRead(ctx context.Context, method, url, body string, options *Options) (*http.Response, []byte, error) {
+	resp, err := do(ctx, method, url, body, options, false)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: tlsConfig,
-			Proxy:           http.ProxyFromEnvironment,
-		},
-	}
+	defer resp.Body.Close()
 
-	req, err := http.NewRequest(method, url, bytes.NewBuffer([]byte(body)))
+	respBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return resp, respBody, err
 	}
 
-	req.Header.Set("Accept", "application/json")
+	if options != nil && options.VerifyDigest {
+		err := verifyDigest(resp.Header, respBody)
+		if err != nil {
+			return resp, nil, err
+		}
+	}
 
-	if method == "PATCH" {
-		req.Header.Set("Content-Type", "application/json-patch+json")
-	} else {
-		req.Header.Set("Content-Type", "application/json")
+	if options != nil && options.RequireBody && len(bytes.TrimSpace(respBody)) == 0 {
+		return resp, nil, fmt.Errorf("%w: %s %s returned %s", ErrEmptyResponse, method, url, resp.Status)
 	}
 
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
+	return resp, respBody, nil
+}
+
+// do sends the HTTP request and returns the response when the status code is 2xx. For all other status codes the
+// error message from the API server is returned. If stream is true the timeout is only applied until the response
+// headers are received.
+func do(ctx context.Context, method, url, body string, options *Options, stream bool) (*http.Response, error) {
+	if options == nil {
+		options = &Options{}
 	}
 
-	if username != "" && password != "" {
-		req.SetBasicAuth(username, password)
+	if options.MaxRequestBytes > 0 && int64(len(body)) > options.MaxRequestBytes {
+		return nil, fmt.Errorf("%w: %d bytes exceed the limit of %d bytes", ErrRequestTooLarge, len(body), options.MaxRequestBytes)
 	}
 
-	resp, err := client.Do(req)
+	url, err := serverURL(url, options)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	defer resp.Body.Close()
-
-	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
-		var apiError APIError
-		err := json.NewDecoder(resp.Body).Decode(&apiError)
+	clientKeyData := options.ClientKeyData
+	if options.ClientKeyPassphrase != "" {
+		decrypted, err := decryptClientKey(clientKeyData, options.ClientKeyPassphrase)
 		if err != nil {
-			return "", fmt.Errorf(resp.Status)
+			return nil, err
 		}
-
-		return "", fmt.Errorf(apiError.Message)
+		clientKeyData = decrypted
 	}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
+	tlsConfig, err := httpClientForRootCAs(options.CertificateAuthorityData, options.ClientCertificateData, clientKeyData, options.InsecureSkipTLSVerify)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return string(respBody), nil
-}
+	transport := &http.Transport{
+		TLSClientConfig:     tlsConfig,
+		Proxy:               http.ProxyFromEnvironment,
+		TLSHandshakeTimeout: time.Duration(options.TLSHandshakeTimeout) * time.Second,
+		// The digest is calculated over the encoded content, so that the body must not be decompressed.
+		DisableCompression: options.VerifyDigest,
+	}
 
-// httpClientForRootCAs return an HTTP client which trusts the provided root CAs.
-func httpClientForRootCAs(certificateAuthorityData, clientCertificateData, clientKeyData string, insecureSkipTLSVerify bool) (*tls.Config, error) {
-	tlsConfig := tls.Config{}
+	if options.ExpectContinue {
+		transport.ExpectContinueTimeout = time.Second
+	}
 
-	if certificateAuthorityData != "" {
-		tlsConfig = tls.Config{RootCAs: x509.NewCertPool()}
-		rootCA := []byte(certificateAuthorityData)
+	if options.IdleTimeout > 0 {
+		transport.DialContext = deadlineDialContext(&net.Dialer{}, time.Duration(options.IdleTimeout)*time.Second)
+	}
 
-		if !tlsConfig.RootCAs.AppendCertsFromPEM(rootCA) {
-			return nil, fmt.Errorf("no certs found in root CA file")
+	var roundTripper http.RoundTripper = transport
+	if options.ClockSkewTolerance > 0 && !options.InsecureSkipTLSVerify {
+		roundTripper = applyClockSkewTolerance(transport, time.Duration(options.ClockSkewTolerance)*time.Second)
+	}
+
+	client := &http.Client{
+		Transport: roundTripper,
+	}
+
+	if len(options.Middlewares) > 0 {
+		client.Transport = chainMiddlewares(roundTripper, options.Middlewares)
+	}
+
+	if options.DisableRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
 		}
 	}
 
-	if clientCertificateData != "" && clientKeyData != "" {
-		cert, err := tls.X509KeyPair([]byte(clientCertificateData), []byte(clientKeyData))
+	if stream {
+		transport.ResponseHeaderTimeout = time.Duration(options.Timeout) * time.Second
+	} else {
+		client.Timeout = time.Duration(options.Timeout) * time.Second
+	}
+
+	var resp *http.Response
+	if options.UpgradeToHTTPS && strings.HasPrefix(url, "http://") {
+		resp, url, err = upgradeToHTTPS(ctx, *client, method, url, body, options)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		tlsConfig.Certificates = []tls.Certificate{cert}
+	// The response of a request without credentials, which wasn't upgraded to https, must not be retried with the
+	// fallback credentials.
+	insecure := resp != nil
+
+	ctx = withRetryTrace(ctx)
+
+	if resp == nil {
+		resp, err = send(ctx, client, method, url, body, options)
+		if err != nil {
+			return nil, retryError(ctx, hostnameError(err))
+		}
 	}
 
-	tlsConfig.InsecureSkipVerify = insecureSkipTLSVerify
+	if !insecure && resp.StatusCode == http.StatusUnauthorized && (options.FallbackToken != "" || options.FallbackUsername != "") {
+		resp.Body.Close()
 
-	return &tlsConfig, nil
-}
+		fallback := *options
+		fallback.Token = options.FallbackToken
+		fallback.Username = options.FallbackUsername
+		fallback.Password = options.FallbackPassword
 
-// AWSGetClusters returns all EKS clusters from AWS.
-func AWSGetClusters(accessKeyId, secretAccessKey, region string) (string, error) {
-	var clusters []*eks.Cluster
-	var names []*string
-	var nextToken *string
+		resp, err = send(ctx, client, method, url, body, &fallback)
+		if err != nil {
+			return nil, retryError(ctx, err)
+		}
+	}
 
-	cred := credentials.NewStaticCredentials(accessKeyId, secretAccessKey, "")
+	if options.DisableRedirects && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		defer resp.Body.Close()
 
-	sess, err := session.NewSession(&aws.Config{Region: aws.String(region), Credentials: cred})
-	if err != nil {
-		return "", err
+		respBody, _ := ioutil.ReadAll(resp.Body)
+
+		return nil, &RedirectError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Location:   resp.Header.Get("Location"),
+			Body:       string(respBody),
+		}
 	}
 
-	eksClient := eks.New(sess)
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		defer resp.Body.Close()
+
+		respBody, _ := ioutil.ReadAll(resp.Body)
 
-	for {
-		c, err := eksClient.ListClusters(&eks.ListClustersInput{NextToken: nextToken})
+		var apiError APIError
+		err := json.Unmarshal(respBody, &apiError)
 		if err != nil {
-			return "", err
+			apiError = APIError{Message: resp.Status, Code: resp.StatusCode}
 		}
+		apiError.Body = string(respBody)
+		apiError.FlowControl = parseFlowControl(resp.Header)
+		apiError.Attempts = retryAttempts(ctx)
 
-		names = append(names, c.Clusters...)
+		return nil, &apiError
+	}
 
-		if c.NextToken == nil {
-			break
-		}
+	resp.Body = &contextReadCloser{ctx: ctx, ReadCloser: resp.Body}
+
+	return resp, nil
+}
 
-		nextToken = c.NextToken
+// contextReadCloser returns the error of the context, when a read fails because the context was cancelled or its
+// deadline was exceeded.
+type contextReadCloser struct {
+	io.ReadCloser
+	ctx context.Context
+}
+
+func (r *contextReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && r.ctx.Err() != nil {
+		return n, r.ctx.Err()
 	}
 
-	for _, name := range names {
-		cluster, err := eksClient.DescribeCluster(&eks.DescribeClusterInput{Name: name})
-		if err != nil {
-			return "", err
+	return n, err
+}
+
+// upgradeToHTTPS sends the request to the http url without credentials. If the server redirects to https on the same
+// host, the https url is returned, otherwise the response of the request without credentials.
+func upgradeToHTTPS(ctx context.Context, client http.Client, method, url, body string, options *Options) (*http.Response, string, error) {
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	anonymous := *options
+	anonymous.Token = ""
+	anonymous.Username = ""
+	anonymous.Password = ""
+	anonymous.Headers = http.Header{}
+	for key, values := range options.Headers {
+		if http.CanonicalHeaderKey(key) != "Authorization" {
+			anonymous.Headers[key] = values
 		}
+	}
+
+	resp, err := send(ctx, &client, method, url, body, &anonymous)
+	if err != nil {
+		return nil, "", err
+	}
 
-		if *cluster.Cluster.Status == eks.ClusterStatusActive {
-			clusters = append(clusters, cluster.Cluster)
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		location, err := resp.Location()
+		if err == nil && location.Scheme == "https" && location.Hostname() == resp.Request.URL.Hostname() {
+			resp.Body.Close()
+			return nil, location.String(), nil
 		}
 	}
 
-	if clusters != nil {
-		b, err := json.Marshal(clusters)
-		if err != nil {
-			return "", err
+	return resp, url, nil
+}
+
+// send creates the HTTP request with the credentials from the options and sends it with the client.
+func send(ctx context.Context, client *http.Client, method, url, body string, options *Options) (*http.Response, error) {
+	req, err := newRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Accept", "application/json")
+
+	if method == "PATCH" {
+		req.Header.Set("Content-Type", "application/json-patch+json")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if options.ExpectContinue && body != "" {
+		req.Header.Set("Expect", "100-continue")
+	}
+
+	if options.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+options.Token)
+	}
+
+	if options.Username != "" && options.Password != "" {
+		req.SetBasicAuth(options.Username, options.Password)
+	}
+
+	for key, values := range options.Headers {
+		key = http.CanonicalHeaderKey(key)
+		if !repeatableHeader(key) {
+			req.Header.Del(key)
 		}
 
-		return string(b), nil
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
 	}
 
-	return "", nil
+	return client.Do(req)
 }
 
-// AWSGetToken returns a bearer token for Kubernetes API requests.
-// See: https://github.com/kubernetes-sigs/aws-iam-authenticator/blob/7547c74e660f8d34d9980f2c69aa008eed1f48d0/pkg/token/token.go#L310
-func AWSGetToken(accessKeyId, secretAccessKey, region, clusterID string) (string, error) {
-	cred := credentials.NewStaticCredentials(accessKeyId, secretAccessKey, "")
+// repeatableHeader returns true for the impersonation headers, which can be sent multiple times.
+func repeatableHeader(key string) bool {
+	return key == "Impersonate-Group" || strings.HasPrefix(key, "Impersonate-Extra-")
+}
+
+// BuildURL returns the URL which is requested by Do for the given url. The URL is parsed and encoded in the same way as
+// for the actual request, so that it can be used to preview a request without sending it.
+func BuildURL(url string) (string, error) {
+	return BuildURLWithOptions(url, nil)
+}
 
-	sess, err := session.NewSession(&aws.Config{Region: aws.String(region), Credentials: cred})
+// BuildURLWithOptions returns the URL which is requested by DoWithOptions for the given url and options. A path is
+// joined with the Server of the options, like for the actual request.
+func BuildURLWithOptions(url string, options *Options) (string, error) {
+	url, err := serverURL(url, options)
 	if err != nil {
 		return "", err
 	}
 
-	stsClient := sts.New(sess)
+	req, err := newRequest(http.MethodGet, url, "")
+	if err != nil {
+		return "", err
+	}
+
+	return req.URL.String(), nil
+}
+
+// serverURL joins the url with the Server of the options via JoinURL. Without a Server the url is returned unchanged.
+func serverURL(url string, options *Options) (string, error) {
+	if options == nil || options.Server == "" {
+		return url, nil
+	}
+
+	return JoinURL(options.Server, url)
+}
+
+// newRequest creates the HTTP request for the given method, url and body.
+func newRequest(method, url, body string) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, bytes.NewBuffer([]byte(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL.Scheme == "" || req.URL.Host == "" {
+		return nil, fmt.Errorf("invalid url %q: scheme and host are required", url)
+	}
+
+	return req, nil
+}
+
+// httpClientForRootCAs return an HTTP client which trusts the provided root CAs.
+func httpClientForRootCAs(certificateAuthorityData, clientCertificateData, clientKeyData string, insecureSkipTLSVerify bool) (*tls.Config, error) {
+	tlsConfig := tls.Config{}
+
+	if certificateAuthorityData != "" {
+		tlsConfig = tls.Config{RootCAs: x509.NewCertPool()}
+		rootCA := []byte(certificateAuthorityData)
+
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(rootCA) {
+			return nil, fmt.Errorf("no certs found in root CA file")
+		}
+	}
+
+	if clientCertificateData != "" && clientKeyData != "" {
+		cert, err := tls.X509KeyPair([]byte(clientCertificateData), []byte(clientKeyData))
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
 
-	request, _ := stsClient.GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
-	request.HTTPRequest.Header.Add("x-k8s-aws-id", clusterID)
-	presignedURLString, err := request.Presign(60)
+	tlsConfig.InsecureSkipVerify = insecureSkipTLSVerify
+
+	return &tlsConfig, nil
+}
+
+// AWSGetClusters returns all EKS clusters from AWS.
+func AWSGetClusters(accessKeyId, secretAccessKey, region string) (string, error) {
+	return AWSGetClustersContext(context.Background(), accessKeyId, secretAccessKey, region)
+}
+
+// AWSGetToken returns a bearer token for Kubernetes API requests. The token is generated locally, so that no context
+// variant is required.
+// See: https://github.com/kubernetes-sigs/aws-iam-authenticator/blob/7547c74e660f8d34d9980f2c69aa008eed1f48d0/pkg/token/token.go#L310
+func AWSGetToken(accessKeyId, secretAccessKey, region, clusterID string) (string, error) {
+	sess, err := awsSession(accessKeyId, secretAccessKey, region)
 	if err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf(`{"token": "k8s-aws-v1.%s"}`, base64.RawURLEncoding.EncodeToString([]byte(presignedURLString))), nil
+	return awsGetToken(sess, clusterID, base64.RawURLEncoding)
 }
 
 // AzureGetClusters return all Kubeconfigs for all AKS clusters for the provided subscription and resource group.
@@ -12,8 +12,14 @@
 package request
 
 import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDoNamespaces(t *testing.T) {
@@ -62,6 +68,31 @@ func TestDoNonexistingResource(t *testing.T) {
 	t.Logf(err.Error())
 }
 
+func TestBuildURL(t *testing.T) {
+	url, err := BuildURL("https://kubernetes.default.svc/api/v1/namespaces/default/pods?labelSelector=app%3Dnginx&limit=10")
+	if err != nil {
+		t.Errorf("Could not build url: %s", err.Error())
+	}
+
+	if url != "https://kubernetes.default.svc/api/v1/namespaces/default/pods?labelSelector=app%3Dnginx&limit=10" {
+		t.Errorf("Unexpected url: %s", url)
+	}
+
+	_, err = BuildURL("/api/v1/namespaces")
+	if err == nil {
+		t.Errorf("Build url without scheme and host instead of error")
+	}
+
+	url, err = BuildURLWithOptions("/api/v1/namespaces", &Options{Server: "https://kubernetes.default.svc/"})
+	if err != nil {
+		t.Errorf("Could not build url with server: %s", err.Error())
+	}
+
+	if url != "https://kubernetes.default.svc/api/v1/namespaces" {
+		t.Errorf("Unexpected url with server: %s", url)
+	}
+}
+
 func TestAWSGetClusters(t *testing.T) {
 	accessKeyId := os.Getenv("AWS_ACCESS_KEY_ID")
 	secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
@@ -147,3 +178,270 @@ func TestOIDCGetAccessToken(t *testing.T) {
 
 	t.Logf(data)
 }
+
+func TestDoWithOptionsFallbackAuth(t *testing.T) {
+	var requests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte(`{"kind": "NamespaceList"}`))
+	}))
+	defer server.Close()
+
+	data, err := DoWithOptions("GET", server.URL+"/api/v1/namespaces", "", &Options{
+		Token:            "expired-token",
+		FallbackUsername: "admin",
+		FallbackPassword: "secret",
+	})
+	if err != nil {
+		t.Fatalf("Could not get namespaces: %s", err.Error())
+	}
+
+	if data != `{"kind": "NamespaceList"}` || requests != 2 {
+		t.Errorf("Unexpected response after %d requests: %s", requests, data)
+	}
+}
+
+func TestDoWithOptionsServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RequestURI()))
+	}))
+	defer server.Close()
+
+	data, err := DoWithOptions("GET", "/api/v1/namespaces?limit=1", "", &Options{Server: server.URL + "/"})
+	if err != nil {
+		t.Fatalf("Could not get namespaces: %s", err.Error())
+	}
+
+	if data != "/api/v1/namespaces?limit=1" {
+		t.Errorf("Unexpected request uri: %s", data)
+	}
+}
+
+func TestDoWithOptionsTLSHandshakeTimeout(t *testing.T) {
+	// The listener accepts connections, but never answers the TLS handshake.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not listen: %s", err.Error())
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	start := time.Now()
+	_, err = DoWithOptions("GET", "https://"+listener.Addr().String()+"/api/v1/namespaces", "", &Options{TLSHandshakeTimeout: 1, Timeout: 30})
+	if err == nil || !strings.Contains(err.Error(), "TLS handshake timeout") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("TLS handshake timeout was not applied")
+	}
+}
+
+func TestDoWithOptionsMaxRequestBytes(t *testing.T) {
+	var requests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	_, err := DoWithOptions("POST", server.URL+"/api/v1/namespaces/default/configmaps", strings.Repeat("a", 11), &Options{MaxRequestBytes: 10})
+	if !errors.Is(err, ErrRequestTooLarge) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	_, err = DoWithOptions("POST", server.URL+"/api/v1/namespaces/default/configmaps", strings.Repeat("a", 10), &Options{MaxRequestBytes: 10})
+	if err != nil {
+		t.Errorf("Could not send request: %s", err.Error())
+	}
+
+	if requests != 1 {
+		t.Errorf("Unexpected number of requests: %d", requests)
+	}
+}
+
+func TestDoWithOptionsRequireBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.Write([]byte(`{"kind": "Namespace"}`))
+	}))
+	defer server.Close()
+
+	_, err := DoWithOptions("GET", server.URL+"/empty", "", &Options{RequireBody: true})
+	if !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	data, err := DoWithOptions("GET", server.URL+"/empty", "", nil)
+	if err != nil || data != "" {
+		t.Errorf("Unexpected response for lenient request: %s, %v", data, err)
+	}
+
+	data, err = DoWithOptions("GET", server.URL+"/object", "", &Options{RequireBody: true})
+	if err != nil || data != `{"kind": "Namespace"}` {
+		t.Errorf("Unexpected response: %s, %v", data, err)
+	}
+}
+
+func TestDoWithOptionsExpectContinue(t *testing.T) {
+	var bodies int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Expect") != "100-continue" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.ContentLength > 1024 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "message": "request entity too large", "reason": "RequestEntityTooLarge", "code": 413}`))
+			return
+		}
+
+		bodies++
+	}))
+	defer server.Close()
+
+	_, err := DoWithOptions("POST", server.URL+"/api/v1/namespaces/default/configmaps", strings.Repeat("a", 8<<20), &Options{ExpectContinue: true})
+
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if apiError.Code != http.StatusRequestEntityTooLarge || apiError.Reason != "RequestEntityTooLarge" || err.Error() != "request entity too large" {
+		t.Errorf("Unexpected api error: %#v", apiError)
+	}
+
+	_, err = DoWithOptions("POST", server.URL+"/api/v1/namespaces/default/configmaps", "{}", &Options{ExpectContinue: true})
+	if err != nil {
+		t.Errorf("Could not send request: %s", err.Error())
+	}
+
+	if bodies != 1 {
+		t.Errorf("Unexpected number of received bodies: %d", bodies)
+	}
+}
+
+func TestDoWithOptionsDisableRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	data, err := DoWithOptions("GET", server.URL+"/old", "", nil)
+	if err != nil || data != "/new" {
+		t.Errorf("Redirect was not followed: %s, %v", data, err)
+	}
+
+	_, err = DoWithOptions("GET", server.URL+"/old", "", &Options{DisableRedirects: true})
+
+	var redirectError *RedirectError
+	if !errors.As(err, &redirectError) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if redirectError.StatusCode != http.StatusFound || redirectError.Location != "/new" {
+		t.Errorf("Unexpected redirect error: %#v", redirectError)
+	}
+}
+
+func TestDoWithOptionsHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Join([]string{
+			strings.Join(r.Header["Accept"], ","),
+			strings.Join(r.Header["Content-Type"], ","),
+			strings.Join(r.Header["Impersonate-User"], ","),
+			strings.Join(r.Header["Impersonate-Group"], ","),
+		}, "|")))
+	}))
+	defer server.Close()
+
+	data, err := DoWithOptions("GET", server.URL, "", &Options{
+		Headers: http.Header{
+			"Accept":            []string{"application/yaml"},
+			"impersonate-user":  []string{"jane"},
+			"Impersonate-Group": []string{"developers", "admins"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Could not send request: %s", err.Error())
+	}
+
+	if data != "application/yaml|application/json|jane|developers,admins" {
+		t.Errorf("Unexpected headers: %s", data)
+	}
+}
+
+func TestDoWithOptionsUpgradeToHTTPS(t *testing.T) {
+	var insecureAuthorization []string
+
+	secureServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.URL.Path + " " + r.Header.Get("Authorization")))
+	}))
+	defer secureServer.Close()
+
+	insecureServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		insecureAuthorization = append(insecureAuthorization, r.Header.Get("Authorization"))
+
+		if r.URL.Path == "/plain" {
+			w.Write([]byte("plain"))
+			return
+		}
+
+		http.Redirect(w, r, secureServer.URL+r.URL.Path, http.StatusMovedPermanently)
+	}))
+	defer insecureServer.Close()
+
+	options := &Options{
+		CertificateAuthorityData: serverCertificateAuthorityData(secureServer),
+		Token:                    "token",
+		UpgradeToHTTPS:           true,
+	}
+
+	data, err := DoWithOptions("POST", insecureServer.URL+"/api/v1/namespaces", "{}", options)
+	if err != nil {
+		t.Fatalf("Could not send request: %s", err.Error())
+	}
+
+	if data != "POST /api/v1/namespaces Bearer token" {
+		t.Errorf("Unexpected response: %s", data)
+	}
+
+	data, err = DoWithOptions("GET", insecureServer.URL+"/plain", "", options)
+	if err != nil || data != "plain" {
+		t.Errorf("Unexpected response for server without redirect: %s, %v", data, err)
+	}
+
+	for _, authorization := range insecureAuthorization {
+		if authorization != "" {
+			t.Errorf("Credentials were sent over http: %s", authorization)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package request
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Response is the response of a request started with DoRaw. The body isn't buffered, so that the data can be processed
+// while it is received. The caller must close the body.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       io.ReadCloser
+	// Attempts contains the trace of all attempts, when the request was sent via the RetryMiddleware.
+	Attempts []RetryAttempt
+
+	resp *http.Response
+}
+
+// DoRaw runs the given HTTP request and returns the response without reading the body. The timeout from the options is
+// only applied until the response headers are received, the context can be used to cancel the request while the body
+// is read.
+func DoRaw(ctx context.Context, method, url, body string, options *Options) (*Response, error) {
+	resp, err := do(ctx, method, url, body, options, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       resp.Body,
+		Attempts:   retryAttempts(resp.Request.Context()),
+		resp:       resp,
+	}, nil
+}
+
+// PeerCertificateSANs returns the DNS and IP subject alternative names of the certificate presented by the server. For
+// requests without TLS nil is returned.
+func (r *Response) PeerCertificateSANs() []string {
+	if r.resp.TLS == nil || len(r.resp.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+
+	return certificateSANs(r.resp.TLS.PeerCertificates[0])
+}
+
+// FlowControl returns the API Priority and Fairness headers of the response.
+func (r *Response) FlowControl() FlowControl {
+	return parseFlowControl(r.Header)
+}
+
+// Trailer returns the HTTP trailers sent by the server. Trailers are only available after the body was read until
+// io.EOF, before that only the announced trailer keys are returned with nil values.
+func (r *Response) Trailer() http.Header {
+	return r.resp.Trailer
+}
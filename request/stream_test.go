@@ -0,0 +1,166 @@
+package request
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDoRaw(t *testing.T) {
+	next := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "line %d\n", i)
+			w.(http.Flusher).Flush()
+
+			select {
+			case <-next:
+			case <-time.After(5 * time.Second):
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	resp, err := DoRaw(context.Background(), "GET", server.URL, "", nil)
+	if err != nil {
+		t.Fatalf("Could not start request: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	// Each line must be received before the server writes the next one.
+	reader := bufio.NewReader(resp.Body)
+	for i := 0; i < 3; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Could not read line %d: %s", i, err.Error())
+		}
+
+		if line != fmt.Sprintf("line %d\n", i) {
+			t.Errorf("Unexpected line: %s", line)
+		}
+
+		next <- struct{}{}
+	}
+}
+
+func TestDoRawTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(w, "line %d\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(400 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	// The stream takes longer than the timeout, but it must not be cut off, because it is progressing.
+	resp, err := DoRaw(context.Background(), "GET", server.URL, "", &Options{Timeout: 1})
+	if err != nil {
+		t.Fatalf("Could not start request: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	var lines int
+	for scanner.Scan() {
+		lines++
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Errorf("Stream was cut off: %s", err.Error())
+	}
+
+	if lines != 4 {
+		t.Errorf("Unexpected number of lines: %d", lines)
+	}
+}
+
+func TestDoRawTrailer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Stream-Status")
+		fmt.Fprintf(w, "data\n")
+		w.Header().Set("X-Stream-Status", "complete")
+	}))
+	defer server.Close()
+
+	resp, err := DoRaw(context.Background(), "GET", server.URL, "", nil)
+	if err != nil {
+		t.Fatalf("Could not start request: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	_, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Could not read body: %s", err.Error())
+	}
+
+	if status := resp.Trailer().Get("X-Stream-Status"); status != "complete" {
+		t.Errorf("Unexpected trailer: %s", status)
+	}
+}
+
+func TestDoContextPartialBody(t *testing.T) {
+	done := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "line 0\n")
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	data, err := DoContext(ctx, "GET", server.URL, "", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if data != "line 0\n" {
+		t.Errorf("Unexpected partial body: %s", data)
+	}
+}
+
+func TestDoRawCanceled(t *testing.T) {
+	done := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "line 0\n")
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp, err := DoRaw(ctx, "GET", server.URL, "", nil)
+	if err != nil {
+		t.Fatalf("Could not start request: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil || line != "line 0\n" {
+		t.Fatalf("Unexpected line: %s, %v", line, err)
+	}
+
+	cancel()
+
+	_, err = reader.ReadString('\n')
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
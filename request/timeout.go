@@ -0,0 +1,87 @@
+package request
+
+// The operation types of the typed helpers, which select the timeout from the OperationTimeouts.
+const (
+	operationGet    = "get"
+	operationList   = "list"
+	operationWatch  = "watch"
+	operationCreate = "create"
+	operationUpdate = "update"
+	operationDelete = "delete"
+)
+
+// OperationTimeouts are the default timeouts in seconds for the typed helpers, e.g. ListAllNamespaces and
+// ListLinkPages use the List timeout, Watch the Watch timeout and Delete the Delete timeout. DoJSON selects the
+// timeout by the HTTP method: GET uses Get, POST uses Create, PUT and PATCH use Update and DELETE uses Delete. A zero
+// value means that requests of the operation don't have a timeout.
+type OperationTimeouts struct {
+	Get    int64
+	List   int64
+	Watch  int64
+	Create int64
+	Update int64
+	Delete int64
+}
+
+// DefaultOperationTimeouts returns the recommended timeouts for the operations. A get should be fast, while a list of
+// a large cluster can take several minutes. A watch has no timeout, because it is expected to run until it is closed.
+func DefaultOperationTimeouts() *OperationTimeouts {
+	return &OperationTimeouts{
+		Get:    30,
+		List:   300,
+		Watch:  0,
+		Create: 60,
+		Update: 60,
+		Delete: 60,
+	}
+}
+
+// timeout returns the timeout for the given operation.
+func (t *OperationTimeouts) timeout(operation string) int64 {
+	switch operation {
+	case operationGet:
+		return t.Get
+	case operationList:
+		return t.List
+	case operationWatch:
+		return t.Watch
+	case operationCreate:
+		return t.Create
+	case operationUpdate:
+		return t.Update
+	case operationDelete:
+		return t.Delete
+	}
+
+	return 0
+}
+
+// methodOperation returns the operation type for the HTTP method of a request sent via DoJSON.
+func methodOperation(method string) string {
+	switch method {
+	case "GET":
+		return operationGet
+	case "POST":
+		return operationCreate
+	case "PUT", "PATCH":
+		return operationUpdate
+	case "DELETE":
+		return operationDelete
+	}
+
+	return ""
+}
+
+// operationOptions returns the options with the timeout for the given operation. The options are returned unchanged
+// when no OperationTimeouts are set or when the Timeout is set, so that a single call can override the default
+// timeout of its operation.
+func operationOptions(options *Options, operation string) *Options {
+	if options == nil || options.OperationTimeouts == nil || options.Timeout != 0 {
+		return options
+	}
+
+	withTimeout := *options
+	withTimeout.Timeout = options.OperationTimeouts.timeout(operation)
+
+	return &withTimeout
+}
@@ -0,0 +1,60 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOperationOptions(t *testing.T) {
+	timeouts := &OperationTimeouts{Get: 1, List: 2, Watch: 3, Create: 4, Update: 5, Delete: 6}
+
+	for _, tt := range []struct {
+		options  *Options
+		method   string
+		expected int64
+	}{
+		{options: &Options{OperationTimeouts: timeouts}, method: "GET", expected: 1},
+		{options: &Options{OperationTimeouts: timeouts}, method: "POST", expected: 4},
+		{options: &Options{OperationTimeouts: timeouts}, method: "PATCH", expected: 5},
+		{options: &Options{OperationTimeouts: timeouts}, method: "DELETE", expected: 6},
+		{options: &Options{OperationTimeouts: timeouts, Timeout: 10}, method: "GET", expected: 10},
+		{options: &Options{Timeout: 10}, method: "GET", expected: 10},
+	} {
+		if timeout := operationOptions(tt.options, methodOperation(tt.method)).Timeout; timeout != tt.expected {
+			t.Errorf("Unexpected timeout for %s with %+v: %d", tt.method, tt.options, timeout)
+		}
+	}
+
+	if operationOptions(nil, operationList) != nil {
+		t.Errorf("Expected nil options to be returned unchanged")
+	}
+
+	if timeout := operationOptions(&Options{OperationTimeouts: DefaultOperationTimeouts()}, operationWatch).Timeout; timeout != 0 {
+		t.Errorf("Expected no default timeout for a watch, got %d", timeout)
+	}
+}
+
+func TestListAllNamespacesOperationTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer server.Close()
+
+	gvr := GroupVersionResource{Version: "v1", Resource: "pods"}
+	options := &Options{OperationTimeouts: &OperationTimeouts{List: 1}}
+
+	_, err := ListAllNamespaces(server.URL, gvr, ListOptions{}, options)
+	if err == nil {
+		t.Errorf("Expected the list timeout to be applied")
+	}
+
+	options.Timeout = 5
+
+	_, err = ListAllNamespaces(server.URL, gvr, ListOptions{}, options)
+	if err != nil {
+		t.Errorf("Expected the Timeout to override the list timeout: %s", err.Error())
+	}
+}
@@ -0,0 +1,126 @@
+package request
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// JoinURL joins the path with the base url of an API server, e.g. "https://cluster.example.com:6443" and
+// "/api/v1/namespaces/default/pods?limit=10". Slashes between the base and the path are handled, so that exactly one
+// slash is used. A query string or fragment of the base is dropped. If path is already an absolute http(s) url, it is
+// returned unchanged.
+func JoinURL(base, path string) (string, error) {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path, nil
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid base url %q: scheme and host are required", base)
+	}
+
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	return strings.TrimSuffix(u.String(), "/") + "/" + strings.TrimPrefix(path, "/"), nil
+}
+
+// GroupVersionResource identifies a Kubernetes resource. The Group is empty for the resources of the core API.
+type GroupVersionResource struct {
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+}
+
+// ResourceURL returns the url of a Kubernetes resource on the API server with the given base url. If the namespace is
+// empty the url of a cluster scoped resource or of a list across all namespaces is returned. If the name is empty the
+// url of the list is returned. ResourceURL is the inverse of ParseResourceURL.
+func ResourceURL(base string, gvr GroupVersionResource, namespace, name string) (string, error) {
+	if gvr.Version == "" || gvr.Resource == "" {
+		return "", fmt.Errorf("invalid resource %v: version and resource are required", gvr)
+	}
+
+	path := "/api/" + gvr.Version
+	if gvr.Group != "" {
+		path = "/apis/" + gvr.Group + "/" + gvr.Version
+	}
+
+	if namespace != "" {
+		path = path + "/namespaces/" + url.PathEscape(namespace)
+	}
+
+	path = path + "/" + gvr.Resource
+
+	if name != "" {
+		path = path + "/" + url.PathEscape(name)
+	}
+
+	return JoinURL(base, path)
+}
+
+// namespaceSubresources are the subresources of a namespace, which can't be told apart from a namespaced resource by
+// the structure of the path.
+var namespaceSubresources = map[string]bool{
+	"status":   true,
+	"finalize": true,
+}
+
+// ParseResourceURL returns the group, version and resource, the namespace and the name from the url of a Kubernetes
+// resource, e.g. "https://cluster.example.com/apis/apps/v1/namespaces/default/deployments/nginx". The namespace and the
+// name are empty for cluster scoped resources and lists. A subresource (e.g. "/status") is ignored. The API path can be
+// prefixed, e.g. by a proxy path like "/k8s/clusters/c-1".
+func ParseResourceURL(resourceURL string) (gvr GroupVersionResource, namespace, name string, err error) {
+	u, err := url.Parse(resourceURL)
+	if err != nil {
+		return GroupVersionResource{}, "", "", err
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	var i int
+	for i = 0; i < len(segments); i++ {
+		if segments[i] == "api" || segments[i] == "apis" {
+			break
+		}
+	}
+
+	if i == len(segments) {
+		return GroupVersionResource{}, "", "", fmt.Errorf("invalid resource url %q: no api path found", resourceURL)
+	}
+
+	if segments[i] == "api" {
+		segments = segments[i+1:]
+	} else {
+		if len(segments) < i+2 {
+			return GroupVersionResource{}, "", "", fmt.Errorf("invalid resource url %q: no api group found", resourceURL)
+		}
+		gvr.Group = segments[i+1]
+		segments = segments[i+2:]
+	}
+
+	if len(segments) < 2 || segments[0] == "" {
+		return GroupVersionResource{}, "", "", fmt.Errorf("invalid resource url %q: no version and resource found", resourceURL)
+	}
+
+	gvr.Version = segments[0]
+	segments = segments[1:]
+
+	// A path like /namespaces/default is the namespace itself, a path like /namespaces/default/pods is a namespaced
+	// resource. The subresources /namespaces/default/status and /namespaces/default/finalize belong to the namespace.
+	if segments[0] == "namespaces" && len(segments) > 2 && !(len(segments) == 3 && namespaceSubresources[segments[2]]) {
+		namespace = segments[1]
+		segments = segments[2:]
+	}
+
+	gvr.Resource = segments[0]
+	if len(segments) > 1 {
+		name = segments[1]
+	}
+
+	return gvr, namespace, name, nil
+}
@@ -0,0 +1,93 @@
+package request
+
+import (
+	"testing"
+)
+
+func TestJoinURL(t *testing.T) {
+	for _, tc := range []struct {
+		base     string
+		path     string
+		expected string
+	}{
+		{base: "https://cluster.example.com:6443", path: "/api/v1/namespaces", expected: "https://cluster.example.com:6443/api/v1/namespaces"},
+		{base: "https://cluster.example.com:6443/", path: "api/v1/namespaces", expected: "https://cluster.example.com:6443/api/v1/namespaces"},
+		{base: "https://cluster.example.com:6443/", path: "/api/v1/pods?labelSelector=app%3Dnginx", expected: "https://cluster.example.com:6443/api/v1/pods?labelSelector=app%3Dnginx"},
+		{base: "https://rancher.example.com/k8s/clusters/c-1/", path: "/api/v1/pods", expected: "https://rancher.example.com/k8s/clusters/c-1/api/v1/pods"},
+		{base: "https://rancher.example.com/k8s/clusters/c-1?a=b", path: "/api", expected: "https://rancher.example.com/k8s/clusters/c-1/api"},
+		{base: "https://cluster.example.com", path: "https://other.example.com/api", expected: "https://other.example.com/api"},
+	} {
+		url, err := JoinURL(tc.base, tc.path)
+		if err != nil {
+			t.Errorf("Could not join %s and %s: %s", tc.base, tc.path, err.Error())
+		}
+
+		if url != tc.expected {
+			t.Errorf("Unexpected url for %s and %s: %s", tc.base, tc.path, url)
+		}
+	}
+
+	_, err := JoinURL("cluster.example.com", "/api")
+	if err == nil {
+		t.Errorf("Join url with invalid base instead of error")
+	}
+}
+
+func TestParseResourceURL(t *testing.T) {
+	for _, tc := range []struct {
+		url       string
+		gvr       GroupVersionResource
+		namespace string
+		name      string
+	}{
+		{url: "https://cluster.example.com/api/v1/pods", gvr: GroupVersionResource{Version: "v1", Resource: "pods"}},
+		{url: "https://cluster.example.com/api/v1/nodes/node-1", gvr: GroupVersionResource{Version: "v1", Resource: "nodes"}, name: "node-1"},
+		{url: "https://cluster.example.com/api/v1/namespaces/default", gvr: GroupVersionResource{Version: "v1", Resource: "namespaces"}, name: "default"},
+		{url: "https://cluster.example.com/api/v1/namespaces/default/status", gvr: GroupVersionResource{Version: "v1", Resource: "namespaces"}, name: "default"},
+		{url: "https://cluster.example.com/api/v1/namespaces/default/finalize", gvr: GroupVersionResource{Version: "v1", Resource: "namespaces"}, name: "default"},
+		{url: "https://cluster.example.com/api/v1/namespaces/default/pods?limit=10", gvr: GroupVersionResource{Version: "v1", Resource: "pods"}, namespace: "default"},
+		{url: "https://cluster.example.com/api/v1/namespaces/default/pods/nginx/log", gvr: GroupVersionResource{Version: "v1", Resource: "pods"}, namespace: "default", name: "nginx"},
+		{url: "https://cluster.example.com/apis/apps/v1/namespaces/default/deployments/nginx", gvr: GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, namespace: "default", name: "nginx"},
+		{url: "https://rancher.example.com/k8s/clusters/c-1/apis/rbac.authorization.k8s.io/v1/clusterroles/admin", gvr: GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}, name: "admin"},
+	} {
+		gvr, namespace, name, err := ParseResourceURL(tc.url)
+		if err != nil {
+			t.Errorf("Could not parse %s: %s", tc.url, err.Error())
+		}
+
+		if gvr != tc.gvr || namespace != tc.namespace || name != tc.name {
+			t.Errorf("Unexpected result for %s: %v, %s, %s", tc.url, gvr, namespace, name)
+		}
+	}
+
+	for _, url := range []string{"https://cluster.example.com/healthz", "https://cluster.example.com/api/v1", "https://cluster.example.com/apis/apps"} {
+		_, _, _, err := ParseResourceURL(url)
+		if err == nil {
+			t.Errorf("Parse invalid resource url %s instead of error", url)
+		}
+	}
+}
+
+func TestResourceURL(t *testing.T) {
+	for _, url := range []string{
+		"https://cluster.example.com/api/v1/pods",
+		"https://cluster.example.com/api/v1/namespaces/default",
+		"https://cluster.example.com/api/v1/namespaces/default/pods/nginx",
+		"https://cluster.example.com/apis/apps/v1/namespaces/default/deployments",
+		"https://cluster.example.com/apis/rbac.authorization.k8s.io/v1/clusterroles/admin",
+	} {
+		gvr, namespace, name, err := ParseResourceURL(url)
+		if err != nil {
+			t.Fatalf("Could not parse %s: %s", url, err.Error())
+		}
+
+		resourceURL, err := ResourceURL("https://cluster.example.com", gvr, namespace, name)
+		if err != nil {
+			t.Errorf("Could not build url for %s: %s", url, err.Error())
+		}
+
+		if resourceURL != url {
+			t.Errorf("Unexpected url for %s: %s", url, resourceURL)
+		}
+	}
+}
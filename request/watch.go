@@ -0,0 +1,119 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// WatchEvent is a single event of a watch request. The Type is ADDED, MODIFIED, DELETED, BOOKMARK or ERROR and the Object
+// is the JSON of the changed object or the Status for an ERROR event.
+type WatchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// WatchOptions contains the settings for the events channel of a watch.
+type WatchOptions struct {
+	// BufferSize is the number of events, which are buffered when the consumer is slower than the API server. If the
+	// value is zero the channel is unbuffered.
+	BufferSize int
+	// DropWhenFull decides what happens when the buffer is full. By default the watch stops reading from the API
+	// server until the consumer receives the next event, so that no event is lost, but the server may close a watch
+	// which falls too far behind. If DropWhenFull is true the event is dropped instead and counted in Dropped. After
+	// an event was dropped the consumer must relist the resource, because its state is incomplete.
+	DropWhenFull bool
+}
+
+// Watcher receives the events of a watch request started with Watch.
+type Watcher struct {
+	// dropped is the first field, so that it is 64-bit aligned for the atomic operations on 32-bit platforms.
+	dropped int64
+
+	// Events contains the decoded events. The channel is closed when the watch ends, afterwards Err returns the reason.
+	Events <-chan WatchEvent
+
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	err error
+}
+
+// Watch starts a watch request for the given url, e.g. "/api/v1/namespaces/default/pods?watch=true", and decodes the
+// events into the Events channel of the returned watcher. The watch runs until the server closes it, the context is
+// cancelled or Close is called.
+func Watch(ctx context.Context, url string, watchOptions WatchOptions, options *Options) (*Watcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	resp, err := DoRaw(ctx, "GET", url, "", operationOptions(options, operationWatch))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	events := make(chan WatchEvent, watchOptions.BufferSize)
+	w := &Watcher{Events: events, cancel: cancel}
+
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var event WatchEvent
+			err := decoder.Decode(&event)
+			if err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					w.setErr(err)
+				}
+				return
+			}
+
+			if watchOptions.DropWhenFull {
+				select {
+				case events <- event:
+				default:
+					atomic.AddInt64(&w.dropped, 1)
+				}
+				continue
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+// Dropped returns the number of events, which were dropped because the buffer was full. Events are only dropped when
+// the DropWhenFull option is set.
+func (w *Watcher) Dropped() int64 {
+	return atomic.LoadInt64(&w.dropped)
+}
+
+// Err returns the error which ended the watch. It is nil while the watch is running and when the watch was closed by
+// the server, the context or Close.
+func (w *Watcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err
+}
+
+// Close stops the watch. The Events channel is closed afterwards.
+func (w *Watcher) Close() {
+	w.cancel()
+}
+
+func (w *Watcher) setErr(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.err = err
+}
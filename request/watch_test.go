@@ -0,0 +1,95 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, `{"type": "ADDED", "object": {"metadata": {"name": "pod-%d"}}}`+"\n", i)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("block", func(t *testing.T) {
+		watcher, err := Watch(context.Background(), server.URL, WatchOptions{BufferSize: 1}, nil)
+		if err != nil {
+			t.Fatalf("Could not start watch: %s", err.Error())
+		}
+		defer watcher.Close()
+
+		var events int
+		for event := range watcher.Events {
+			time.Sleep(10 * time.Millisecond)
+			if event.Type != "ADDED" || string(event.Object) != fmt.Sprintf(`{"metadata": {"name": "pod-%d"}}`, events) {
+				t.Errorf("Unexpected event: %s %s", event.Type, event.Object)
+			}
+			events++
+		}
+
+		if events != 5 || watcher.Dropped() != 0 || watcher.Err() != nil {
+			t.Errorf("Unexpected result: %d events, %d dropped, %v", events, watcher.Dropped(), watcher.Err())
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		watcher, err := Watch(context.Background(), server.URL, WatchOptions{BufferSize: 1, DropWhenFull: true}, nil)
+		if err != nil {
+			t.Fatalf("Could not start watch: %s", err.Error())
+		}
+		defer watcher.Close()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for watcher.Dropped() < 4 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		var events int
+		for range watcher.Events {
+			events++
+		}
+
+		if events != 1 || watcher.Dropped() != 4 {
+			t.Errorf("Unexpected result: %d events, %d dropped", events, watcher.Dropped())
+		}
+	})
+}
+
+func TestWatchClose(t *testing.T) {
+	done := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"type": "ADDED", "object": {}}`+"\n")
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	watcher, err := Watch(context.Background(), server.URL, WatchOptions{}, nil)
+	if err != nil {
+		t.Fatalf("Could not start watch: %s", err.Error())
+	}
+
+	<-watcher.Events
+	watcher.Close()
+
+	select {
+	case _, ok := <-watcher.Events:
+		if ok {
+			t.Errorf("Received event after close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Events channel wasn't closed")
+	}
+
+	if watcher.Err() != nil {
+		t.Errorf("Unexpected error after close: %s", watcher.Err().Error())
+	}
+}